@@ -0,0 +1,584 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/chirpimport"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+	"github.com/google/uuid"
+)
+
+const adminUsage = `Usage: chirpy admin <command> [flags] [arguments]
+
+Commands:
+  users list                  List users, newest first
+  users search <text>         List users whose email contains text
+  red grant <user>            Give a user Chirpy Red
+  red revoke <user>           End a user's Chirpy Red subscription now
+  roles grant <user> <role>   Give a user a role (admin or moderator)
+  roles revoke <user> <role>  Take a role away from a user
+  suspend <user>              Suspend an account and sign it out everywhere
+  unsuspend <user>            Let a suspended account log in again
+  revoke-sessions <user>      Revoke all of a user's refresh tokens
+  chirps delete <chirpID>     Delete a chirp
+  chirps import <user> <file> Import a JSON Lines or CSV archive of chirps
+  stats                       Print user and chirp counts
+
+<user> is a user ID or email. Every command takes -format table or json,
+before its arguments. Run "chirpy admin <command> -h" for its other flags.
+`
+
+// Roles operators can grant. Admins can use the admin endpoints with their
+// access JWT, moderators and admins can delete anyone's chirps.
+const (
+	roleAdmin     = "admin"
+	roleModerator = "moderator"
+)
+
+var knownRoles = map[string]bool{
+	roleAdmin:     true,
+	roleModerator: true,
+}
+
+const defaultAdminListLimit = 50
+
+var errAdminUsage = errors.New("invalid command, see chirpy admin -h")
+
+// runAdmin runs `chirpy admin`, the operator commands that work directly
+// against the database instead of through the API
+func runAdmin(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprint(stderr, adminUsage)
+		return nil
+	}
+
+	cfg, err := loadAdminConfig()
+	if err != nil {
+		return err
+	}
+	defer cfg.db.Close()
+	admin := &adminCLI{cfg: cfg, stdout: stdout, stderr: stderr}
+
+	ctx := context.Background()
+	command, rest := args[0], args[1:]
+	switch command {
+	case "users", "red", "roles", "chirps":
+		if len(rest) == 0 {
+			return errAdminUsage
+		}
+		command += " " + rest[0]
+		rest = rest[1:]
+	}
+
+	switch command {
+	case "users list":
+		return admin.listUsers(ctx, command, rest, false)
+	case "users search":
+		return admin.listUsers(ctx, command, rest, true)
+	case "red grant":
+		return admin.grantChirpyRed(ctx, command, rest)
+	case "red revoke":
+		return admin.revokeChirpyRed(ctx, command, rest)
+	case "roles grant":
+		return admin.setRole(ctx, command, rest, true)
+	case "roles revoke":
+		return admin.setRole(ctx, command, rest, false)
+	case "suspend":
+		return admin.suspend(ctx, command, rest)
+	case "unsuspend":
+		return admin.unsuspend(ctx, command, rest)
+	case "revoke-sessions":
+		return admin.revokeSessions(ctx, command, rest)
+	case "chirps delete":
+		return admin.deleteChirp(ctx, command, rest)
+	case "chirps import":
+		return admin.importChirps(ctx, command, rest)
+	case "stats":
+		return admin.stats(ctx, command, rest)
+	default:
+		return errAdminUsage
+	}
+}
+
+// loadAdminConfig connects to DB_URL. Events from admin commands reach live
+// subscribers of running servers through the postgres events backend. With
+// the memory backend there is nobody to tell.
+func loadAdminConfig() (*apiConfig, error) {
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		return nil, errors.New("DB_URL must be set")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, err
+	}
+	entitlementsService, err := loadEntitlements()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &apiConfig{
+		db:           db,
+		dbQueries:    database.New(db),
+		platform:     os.Getenv("PLATFORM"),
+		entitlements: entitlementsService,
+	}
+	if os.Getenv("EVENTS_BACKEND") == "postgres" {
+		cfg.events = &pubsub.PostgresPublisher{DB: db, Channel: pubsub.DefaultChannel}
+	} else {
+		cfg.events = pubsub.NewHub(0)
+	}
+	return cfg, nil
+}
+
+type adminCLI struct {
+	cfg    *apiConfig
+	stdout io.Writer
+	stderr io.Writer
+	format string
+}
+
+// flags returns the flag set for command with the -format flag every
+// command has
+func (a *adminCLI) flags(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet("chirpy admin "+command, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.StringVar(&a.format, "format", "table", "output format, table or json")
+	return flags
+}
+
+// parse parses args into flags and returns exactly want positional
+// arguments
+func (a *adminCLI) parse(flags *flag.FlagSet, args []string, want ...string) ([]string, error) {
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if a.format != "table" && a.format != "json" {
+		return nil, fmt.Errorf("-format must be table or json, not %q", a.format)
+	}
+	if flags.NArg() != len(want) {
+		return nil, fmt.Errorf("%s takes %d arguments: %s", flags.Name(), len(want), strings.Join(want, " "))
+	}
+	return flags.Args(), nil
+}
+
+// print writes v as JSON, or as a table of header and rows
+func (a *adminCLI) print(v any, header []string, rows [][]string) error {
+	if a.format == "json" {
+		encoder := json.NewEncoder(a.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	table := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	return table.Flush()
+}
+
+// AdminUserJSON is a user as admin commands print them
+type AdminUserJSON struct {
+	ID              uuid.UUID  `json:"id"`
+	Email           string     `json:"email"`
+	CreatedAt       time.Time  `json:"created_at"`
+	IsEmailVerified bool       `json:"is_email_verified"`
+	IsChirpyRed     bool       `json:"is_chirpy_red"`
+	Roles           []string   `json:"roles"`
+	SuspendedAt     *time.Time `json:"suspended_at"`
+}
+
+func newAdminUserJSON(user database.User, isChirpyRed bool) AdminUserJSON {
+	adminUser := AdminUserJSON{
+		ID:              user.ID,
+		Email:           user.Email,
+		CreatedAt:       user.CreatedAt.Time,
+		IsEmailVerified: user.EmailVerifiedAt.Valid,
+		IsChirpyRed:     isChirpyRed,
+		Roles:           user.Roles,
+	}
+	if adminUser.Roles == nil {
+		adminUser.Roles = []string{}
+	}
+	if user.SuspendedAt.Valid {
+		adminUser.SuspendedAt = &user.SuspendedAt.Time
+	}
+	return adminUser
+}
+
+func (a *adminCLI) printUsers(users []AdminUserJSON) error {
+	rows := [][]string{}
+	for _, user := range users {
+		suspended := ""
+		if user.SuspendedAt != nil {
+			suspended = user.SuspendedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			user.ID.String(),
+			user.Email,
+			user.CreatedAt.Format(time.RFC3339),
+			strconv.FormatBool(user.IsEmailVerified),
+			strconv.FormatBool(user.IsChirpyRed),
+			strings.Join(user.Roles, ","),
+			suspended,
+		})
+	}
+	header := []string{"ID", "EMAIL", "CREATED", "VERIFIED", "RED", "ROLES", "SUSPENDED"}
+	return a.print(users, header, rows)
+}
+
+// printUser prints a user after a command changed it
+func (a *adminCLI) printUser(ctx context.Context, user database.User) error {
+	return a.printUsers([]AdminUserJSON{newAdminUserJSON(user, a.cfg.isChirpyRed(ctx, user.ID))})
+}
+
+// parseUserRef tells whether ref is a user ID or an email. It returns the
+// ID, or uuid.Nil and the email.
+func parseUserRef(ref string) (uuid.UUID, string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return uuid.Nil, "", errors.New("a user ID or email is required")
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		return id, "", nil
+	}
+	return uuid.Nil, ref, nil
+}
+
+// findUser looks a user up by ID or email
+func (a *adminCLI) findUser(ctx context.Context, ref string) (database.User, error) {
+	id, email, err := parseUserRef(ref)
+	if err != nil {
+		return database.User{}, err
+	}
+	var user database.User
+	if id != uuid.Nil {
+		user, err = a.cfg.dbQueries.GetUserByID(ctx, id)
+	} else {
+		user, err = a.cfg.dbQueries.GetUserByEmail(ctx, email)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("no user %s", ref)
+	}
+	return user, err
+}
+
+func (a *adminCLI) listUsers(ctx context.Context, command string, args []string, search bool) error {
+	flags := a.flags(command)
+	limit := flags.Int("limit", defaultAdminListLimit, "number of users to list")
+	want := []string{}
+	if search {
+		want = append(want, "<text>")
+	}
+	args, err := a.parse(flags, args, want...)
+	if err != nil {
+		return err
+	}
+	if *limit < 1 {
+		return errors.New("-limit must be at least 1")
+	}
+
+	params := database.ListUsersParams{MaxUsers: int32(*limit)}
+	if search {
+		params.Search = sql.NullString{String: args[0], Valid: true}
+	}
+	rows, err := a.cfg.dbQueries.ListUsers(ctx, params)
+	if err != nil {
+		return err
+	}
+	users := []AdminUserJSON{}
+	for _, row := range rows {
+		users = append(users, newAdminUserJSON(database.User{
+			ID:              row.ID,
+			Email:           row.Email,
+			CreatedAt:       row.CreatedAt,
+			EmailVerifiedAt: row.EmailVerifiedAt,
+			Roles:           row.Roles,
+			SuspendedAt:     row.SuspendedAt,
+		}, row.IsChirpyRed))
+	}
+	return a.printUsers(users)
+}
+
+func (a *adminCLI) grantChirpyRed(ctx context.Context, command string, args []string) error {
+	flags := a.flags(command)
+	period := flags.Duration("period", subscriptions.DefaultPeriod, "how long the subscription lasts")
+	args, err := a.parse(flags, args, "<user>")
+	if err != nil {
+		return err
+	}
+	if *period <= 0 {
+		return errors.New("-period must be positive")
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	periodEnd := time.Now().Add(*period)
+	err = a.cfg.applySubscriptionEvent(ctx, user.ID, subscriptions.EventUpgraded, subscriptions.PlanChirpyRed, periodEnd, uuid.NullUUID{})
+	if err != nil {
+		return err
+	}
+	return a.printUser(ctx, user)
+}
+
+func (a *adminCLI) revokeChirpyRed(ctx context.Context, command string, args []string) error {
+	args, err := a.parse(a.flags(command), args, "<user>")
+	if err != nil {
+		return err
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	err = a.cfg.applySubscriptionEvent(ctx, user.ID, subscriptions.EventDowngraded, subscriptions.PlanChirpyRed, time.Time{}, uuid.NullUUID{})
+	if errors.Is(err, subscriptions.ErrNoSubscription) {
+		return fmt.Errorf("%s has never had Chirpy Red", user.Email)
+	}
+	if err != nil {
+		return err
+	}
+	return a.printUser(ctx, user)
+}
+
+func (a *adminCLI) setRole(ctx context.Context, command string, args []string, grant bool) error {
+	args, err := a.parse(a.flags(command), args, "<user>", "<role>")
+	if err != nil {
+		return err
+	}
+	role := args[1]
+	if !knownRoles[role] {
+		return fmt.Errorf("unknown role %q, roles are %s and %s", role, roleAdmin, roleModerator)
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if grant {
+		user, err = a.cfg.dbQueries.AddUserRole(ctx, database.AddUserRoleParams{ID: user.ID, Role: role})
+	} else {
+		user, err = a.cfg.dbQueries.RemoveUserRole(ctx, database.RemoveUserRoleParams{ID: user.ID, Role: role})
+	}
+	if err != nil {
+		return err
+	}
+	return a.printUser(ctx, user)
+}
+
+// suspend stops a user logging in and revokes their refresh and personal
+// access tokens. Access tokens they already hold work until they expire.
+func (a *adminCLI) suspend(ctx context.Context, command string, args []string) error {
+	args, err := a.parse(a.flags(command), args, "<user>")
+	if err != nil {
+		return err
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := a.cfg.dbQueries.WithTx(tx)
+	user, err = qtx.SuspendUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	_, err = qtx.RevokeAllRefreshTokensForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	err = qtx.RevokeAllPersonalAccessTokensForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	return a.printUser(ctx, user)
+}
+
+func (a *adminCLI) unsuspend(ctx context.Context, command string, args []string) error {
+	args, err := a.parse(a.flags(command), args, "<user>")
+	if err != nil {
+		return err
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	user, err = a.cfg.dbQueries.UnsuspendUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return a.printUser(ctx, user)
+}
+
+func (a *adminCLI) revokeSessions(ctx context.Context, command string, args []string) error {
+	args, err := a.parse(a.flags(command), args, "<user>")
+	if err != nil {
+		return err
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	revoked, err := a.cfg.dbQueries.RevokeAllRefreshTokensForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		UserID  uuid.UUID `json:"user_id"`
+		Revoked int64     `json:"revoked"`
+	}{user.ID, revoked}
+	return a.print(result, []string{"USER ID", "REVOKED"}, [][]string{
+		{user.ID.String(), strconv.FormatInt(revoked, 10)},
+	})
+}
+
+func (a *adminCLI) deleteChirp(ctx context.Context, command string, args []string) error {
+	args, err := a.parse(a.flags(command), args, "<chirpID>")
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid chirp ID %q", args[0])
+	}
+	chirp, err := a.cfg.dbQueries.GetChirp(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no chirp %s", id)
+	}
+	if err != nil {
+		return err
+	}
+	err = a.cfg.removeChirp(ctx, chirp)
+	if err != nil {
+		return err
+	}
+	deleted := newChirpJSON(chirp)
+	return a.print(deleted, []string{"ID", "USER ID", "CREATED", "BODY"}, [][]string{
+		{chirp.ID.String(), chirp.UserID.String(), chirp.CreatedAt.Format(time.RFC3339), chirp.Body},
+	})
+}
+
+// importChirps runs an import right away instead of leaving it to a server
+func (a *adminCLI) importChirps(ctx context.Context, command string, args []string) error {
+	flags := a.flags(command)
+	input := flags.String("input", "", "archive format, jsonl or csv (default from the file extension)")
+	args, err := a.parse(flags, args, "<user>", "<file>")
+	if err != nil {
+		return err
+	}
+	format := *input
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(args[1])) {
+		case ".jsonl", ".ndjson":
+			format = chirpimport.FormatJSONL
+		case ".csv":
+			format = chirpimport.FormatCSV
+		default:
+			return errors.New("can't tell the archive format from the file name, set -input")
+		}
+	}
+	if format != chirpimport.FormatJSONL && format != chirpimport.FormatCSV {
+		return fmt.Errorf("-input must be jsonl or csv, not %q", format)
+	}
+	user, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	archive, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	// Leased from the start so running servers leave it alone
+	created, err := a.cfg.dbQueries.CreateChirpImport(ctx, database.CreateChirpImportParams{
+		UserID:        user.ID,
+		Format:        format,
+		Archive:       archive,
+		NextAttemptAt: time.Now().Add(chirpImportLease),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s already has a pending import", user.Email)
+	}
+	if err != nil {
+		return err
+	}
+	a.cfg.runChirpImport(ctx, created.ID)
+	chirpImport, err := a.cfg.dbQueries.GetChirpImport(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+
+	result := newChirpImportJSON(chirpImport)
+	err = a.print(result, []string{"ID", "STATUS", "TOTAL", "IMPORTED", "DUPLICATES", "FAILED"}, [][]string{{
+		result.ID.String(),
+		result.Status,
+		strconv.Itoa(int(result.TotalRows)),
+		strconv.Itoa(int(result.ImportedRows)),
+		strconv.Itoa(int(result.DuplicateRows)),
+		strconv.Itoa(int(result.FailedRows)),
+	}})
+	if err != nil {
+		return err
+	}
+	if a.format == "table" {
+		if result.Error != "" {
+			fmt.Fprintln(a.stderr, result.Error)
+		}
+		for _, rowError := range result.Errors {
+			fmt.Fprintf(a.stderr, "line %d: %s\n", rowError.Line, rowError.Error)
+		}
+	}
+	return nil
+}
+
+func (a *adminCLI) stats(ctx context.Context, command string, args []string) error {
+	_, err := a.parse(a.flags(command), args)
+	if err != nil {
+		return err
+	}
+	stats, err := a.cfg.dbQueries.GetStats(ctx)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Users          int64 `json:"users"`
+		VerifiedUsers  int64 `json:"verified_users"`
+		SuspendedUsers int64 `json:"suspended_users"`
+		ChirpyRedUsers int64 `json:"chirpy_red_users"`
+		Chirps         int64 `json:"chirps"`
+		ChirpsLastDay  int64 `json:"chirps_last_day"`
+		ActiveSessions int64 `json:"active_sessions"`
+	}(stats)
+	rows := [][]string{
+		{"users", strconv.FormatInt(stats.Users, 10)},
+		{"verified_users", strconv.FormatInt(stats.VerifiedUsers, 10)},
+		{"suspended_users", strconv.FormatInt(stats.SuspendedUsers, 10)},
+		{"chirpy_red_users", strconv.FormatInt(stats.ChirpyRedUsers, 10)},
+		{"chirps", strconv.FormatInt(stats.Chirps, 10)},
+		{"chirps_last_day", strconv.FormatInt(stats.ChirpsLastDay, 10)},
+		{"active_sessions", strconv.FormatInt(stats.ActiveSessions, 10)},
+	}
+	return a.print(result, []string{"STAT", "COUNT"}, rows)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestRunAdminWithoutDatabase(t *testing.T) {
+	t.Setenv("DB_URL", "")
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantErr   string
+		wantUsage bool
+	}{
+		{name: "No command", args: nil, wantUsage: true},
+		{name: "Help flag", args: []string{"-h"}, wantUsage: true},
+		{name: "Help command", args: []string{"help"}, wantUsage: true},
+		{name: "Command needs DB_URL", args: []string{"stats"}, wantErr: "DB_URL must be set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			err := runAdmin(tt.args, stdout, stderr)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runAdmin() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("runAdmin() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := stderr.String() == adminUsage; got != tt.wantUsage {
+				t.Errorf("runAdmin() printed usage = %v, want %v", got, tt.wantUsage)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("runAdmin() wrote %q to stdout", stdout.String())
+			}
+		})
+	}
+}
+
+func TestAdminParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       []string
+		wantArgs   []string
+		wantFormat string
+		wantErr    bool
+	}{
+		{name: "No arguments", args: nil, wantFormat: "table"},
+		{name: "Arguments", args: []string{"user@example.com", "admin"}, want: []string{"user", "role"}, wantArgs: []string{"user@example.com", "admin"}, wantFormat: "table"},
+		{name: "JSON format", args: []string{"-format", "json", "user@example.com"}, want: []string{"user"}, wantArgs: []string{"user@example.com"}, wantFormat: "json"},
+		{name: "Unknown format", args: []string{"-format", "yaml"}, wantErr: true},
+		{name: "Unknown flag", args: []string{"-verbose"}, wantErr: true},
+		{name: "Missing argument", args: []string{"user@example.com"}, want: []string{"user", "role"}, wantErr: true},
+		{name: "Extra argument", args: []string{"user@example.com", "admin"}, want: []string{"user"}, wantErr: true},
+		{name: "Flag after arguments", args: []string{"user@example.com", "-format", "json"}, want: []string{"user"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin := &adminCLI{stdout: &bytes.Buffer{}, stderr: &bytes.Buffer{}}
+			got, err := admin.parse(admin.flags("test"), tt.args, tt.want...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(got, tt.wantArgs) {
+				t.Errorf("parse() = %q, want %q", got, tt.wantArgs)
+			}
+			if admin.format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", admin.format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestParseUserRef(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name      string
+		ref       string
+		wantID    uuid.UUID
+		wantEmail string
+		wantErr   bool
+	}{
+		{name: "ID", ref: id.String(), wantID: id},
+		{name: "Email", ref: "user@example.com", wantEmail: "user@example.com"},
+		{name: "Surrounding spaces", ref: "  user@example.com\n", wantEmail: "user@example.com"},
+		{name: "Not quite an ID", ref: id.String()[1:], wantEmail: id.String()[1:]},
+		{name: "Empty", ref: "", wantErr: true},
+		{name: "Blank", ref: "   ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotEmail, err := parseUserRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUserRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotID != tt.wantID || gotEmail != tt.wantEmail {
+				t.Errorf("parseUserRef() = %v, %q, want %v, %q", gotID, gotEmail, tt.wantID, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestAdminPrintUsers(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	suspendedAt := time.Date(2025, 6, 7, 8, 9, 10, 0, time.UTC)
+	users := []AdminUserJSON{
+		{
+			ID:              uuid.MustParse("6f1c0c4e-52a8-4b8b-9d1e-0a3f6b2c9d11"),
+			Email:           "admin@example.com",
+			CreatedAt:       createdAt,
+			IsEmailVerified: true,
+			Roles:           []string{roleAdmin, roleModerator},
+		},
+		{
+			ID:          uuid.MustParse("0b7e3a52-9c4d-4f1e-8a6b-2d5c7e9f1a23"),
+			Email:       "suspended@example.com",
+			CreatedAt:   createdAt,
+			IsChirpyRed: true,
+			Roles:       []string{},
+			SuspendedAt: &suspendedAt,
+		},
+	}
+
+	t.Run("Table", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+		admin := &adminCLI{stdout: stdout, format: "table"}
+		err := admin.printUsers(users)
+		if err != nil {
+			t.Fatalf("printUsers() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		want := [][]string{
+			{"ID", "EMAIL", "CREATED", "VERIFIED", "RED", "ROLES", "SUSPENDED"},
+			{"6f1c0c4e-52a8-4b8b-9d1e-0a3f6b2c9d11", "admin@example.com", "2025-01-02T03:04:05Z", "true", "false", "admin,moderator"},
+			{"0b7e3a52-9c4d-4f1e-8a6b-2d5c7e9f1a23", "suspended@example.com", "2025-01-02T03:04:05Z", "false", "true", "2025-06-07T08:09:10Z"},
+		}
+		if len(lines) != len(want) {
+			t.Fatalf("printUsers() printed %d lines, want %d:\n%s", len(lines), len(want), stdout)
+		}
+		for i, line := range lines {
+			if got := strings.Fields(line); !reflect.DeepEqual(got, want[i]) {
+				t.Errorf("line %d = %q, want %q", i, got, want[i])
+			}
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+		admin := &adminCLI{stdout: stdout, format: "json"}
+		err := admin.printUsers(users)
+		if err != nil {
+			t.Fatalf("printUsers() error = %v", err)
+		}
+		got := []AdminUserJSON{}
+		err = json.Unmarshal(stdout.Bytes(), &got)
+		if err != nil {
+			t.Fatalf("printUsers() printed invalid JSON: %v", err)
+		}
+		if !reflect.DeepEqual(got, users) {
+			t.Errorf("printUsers() = %+v, want %+v", got, users)
+		}
+		if !strings.Contains(stdout.String(), `"suspended_at": null`) {
+			t.Errorf("printUsers() = %s, want a null suspended_at for active users", stdout)
+		}
+	})
+}
+
+func TestHasRole(t *testing.T) {
+	suspendedAt := sql.NullTime{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name  string
+		user  database.User
+		roles []string
+		want  bool
+	}{
+		{name: "Granted", user: database.User{Roles: []string{roleModerator}}, roles: []string{roleModerator}, want: true},
+		{name: "Any of several", user: database.User{Roles: []string{roleAdmin}}, roles: []string{roleModerator, roleAdmin}, want: true},
+		{name: "Other role", user: database.User{Roles: []string{roleModerator}}, roles: []string{roleAdmin}, want: false},
+		{name: "No roles", user: database.User{}, roles: []string{roleAdmin}, want: false},
+		{name: "Suspended", user: database.User{Roles: []string{roleAdmin}, SuspendedAt: suspendedAt}, roles: []string{roleAdmin}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasRole(tt.user, tt.roles...); got != tt.want {
+				t.Errorf("hasRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Only the cases that are decided before looking the user up, the config
+// has no database
+func TestMiddlewareAdmin(t *testing.T) {
+	keyring := newTestKeyring(t, "admin-test-secret")
+	forged, err := newTestKeyring(t, "another-secret").MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pat, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		adminAPIKey   string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "API key", adminAPIKey: "secret", authorization: "ApiKey secret", wantStatus: http.StatusOK},
+		{name: "Wrong API key", adminAPIKey: "secret", authorization: "ApiKey wrong", wantStatus: http.StatusUnauthorized},
+		{name: "No API key configured", authorization: "ApiKey secret", wantStatus: http.StatusUnauthorized},
+		{name: "No header", adminAPIKey: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "Personal access token", adminAPIKey: "secret", authorization: "Bearer " + pat, wantStatus: http.StatusForbidden},
+		{name: "Forged JWT", authorization: "Bearer " + forged, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{keyring: keyring, adminAPIKey: tt.adminAPIKey}
+			handler := cfg.middlewareAdmin(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/events", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
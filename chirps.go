@@ -1,19 +1,28 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/RodolfoCamposGlz/internal/auth"
 	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/entitlements"
+	"github.com/RodolfoCamposGlz/internal/httpcache"
 	"github.com/google/uuid"
 )
 
+const (
+	// chirpCacheControl lets clients and proxies reuse a chirp for a
+	// minute, chirps only change by being deleted
+	chirpCacheControl = "public, max-age=60"
+	// chirpsCacheControl has the list revalidated every time, which is
+	// cheap with the ETag
+	chirpsCacheControl = "public, no-cache"
+)
 
 type ChirpJSON struct {
 	Body   string    `json:"body"`
@@ -24,14 +33,14 @@ type ChirpJSON struct {
 }
 
 
-func (cfg *apiConfig) handlerValidateChirp(w http.ResponseWriter, chirp *database.Chirp) (string, error) {
-	if len(chirp.Body) > 140 {
-		respondWithError(w, 400, "Chirp is too long")
-		return "", fmt.Errorf("chirp too long")
+// validateChirp checks a chirp's length and censors profanity
+func validateChirp(body string, limits entitlements.Entitlements) (string, *apiError) {
+	if len(body) > limits.MaxChirpLength {
+		return "", &apiError{status: http.StatusBadRequest, message: "Chirp is too long"}
 	}
 
 	//Clean body input
-	splitWords := strings.Split(chirp.Body, " ")
+	splitWords := strings.Split(body, " ")
 	profanityMap := map[string]bool{
 		"kerfuffle": true,
 		"sharbert":  true,
@@ -50,22 +59,7 @@ func (cfg *apiConfig) handlerValidateChirp(w http.ResponseWriter, chirp *databas
 }
 
 
-func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
-
-
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT")
-		return
-	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT")
-		return
-	}
-
+func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
     chirpRequest := ChirpJSON{}
     if err := json.NewDecoder(r.Body).Decode(&chirpRequest); err != nil {
         log.Printf("Error decoding JSON: %v", err)
@@ -73,37 +67,73 @@ func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    // Map ChirpJSON to Chirp structure for validation
-    chirp := database.Chirp{
-        Body:   chirpRequest.Body,
-        UserID: userID,
-    }
+	newChirp, apiErr := cfg.createChirp(r.Context(), userID, chirpRequest.Body)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newChirpJSON(newChirp))
+}
 
-    // Validate the chirp's body
-    cleanedBody, err := cfg.handlerValidateChirp(w, &chirp)
-    if err != nil {
-        return // Validation failed, already handled in handlerValidateChirp
-    }
+// createChirp posts a chirp as userID within their plan's limits and
+// notifies webhooks and live subscribers
+func (cfg *apiConfig) createChirp(ctx context.Context, userID uuid.UUID, body string) (database.Chirp, *apiError) {
+	allowed, err := cfg.checkVerifiedFor(ctx, userID, restrictPostChirps)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return database.Chirp{}, &apiError{status: http.StatusInternalServerError, message: "Error checking email verification"}
+	}
+	if !allowed {
+		return database.Chirp{}, &apiError{status: http.StatusForbidden, message: "Verify your email address to post chirps"}
+	}
 
+	_, limits, err := cfg.userEntitlements(ctx, userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return database.Chirp{}, &apiError{status: http.StatusInternalServerError, message: "Error getting entitlements"}
+	}
+	if limits.ChirpsPerHour > 0 {
+		posted, err := cfg.dbQueries.CountChirpsByAuthorSince(ctx, database.CountChirpsByAuthorSinceParams{
+			UserID:    userID,
+			CreatedAt: time.Now().Add(-time.Hour),
+		})
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			return database.Chirp{}, &apiError{status: http.StatusInternalServerError, message: "Error checking chirp rate limit"}
+		}
+		if posted >= int64(limits.ChirpsPerHour) {
+			return database.Chirp{}, &apiError{status: http.StatusTooManyRequests, message: "Too many chirps, try again later"}
+		}
+	}
+
+	cleanedBody, apiErr := validateChirp(body, limits)
+	if apiErr != nil {
+		return database.Chirp{}, apiErr
+	}
 
 	params := database.CreateChirpParams{
 		Body: cleanedBody,
-		UserID: chirp.UserID,
+		UserID: userID,
 	}
-	newChirp, err := cfg.dbQueries.CreateChirp(r.Context(), params)
+	newChirp, err := cfg.dbQueries.CreateChirp(ctx, params)
 	if err != nil {
 		log.Println("Error creating chirp", err)
-		respondWithError(w, http.StatusInternalServerError, "Error creating chirp")
-		return
+		return database.Chirp{}, &apiError{status: http.StatusInternalServerError, message: "Error creating chirp"}
 	}
-	response := ChirpJSON{
-		ID: newChirp.ID,
-		Body: newChirp.Body,
-		CreatedAt: newChirp.CreatedAt,
-		UpdatedAt: newChirp.UpdatedAt,
-		UserID: newChirp.UserID,
+	response := newChirpJSON(newChirp)
+	cfg.queueOutboundWebhook(ctx, newChirp.UserID, eventChirpCreated, response)
+	cfg.publishChirpEvent(ctx, eventChirpCreated, newChirp, response)
+	return newChirp, nil
+}
+
+func newChirpJSON(chirp database.Chirp) ChirpJSON {
+	return ChirpJSON{
+		ID:        chirp.ID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		UserID:    chirp.UserID,
 	}
-	respondWithJSON(w, http.StatusCreated, response)
 }
 
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
@@ -122,49 +152,69 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var chirps []database.Chirp
-	var err error
-
+	authorID := uuid.NullUUID{}
 	if authorId != "" {
 		authorUUID, err := uuid.Parse(authorId)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid author ID")
 			return
 		}
-		chirps, err = cfg.dbQueries.GetChirpsByAuthorID(r.Context(), authorUUID)
-	} else {
-		chirps, err = cfg.dbQueries.GetChirps(r.Context())
+		authorID = uuid.NullUUID{UUID: authorUUID, Valid: true}
 	}
 
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+	chirps, apiErr := cfg.listChirps(r.Context(), authorID, sortOrder == "desc")
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	// No Last-Modified, deleting a chirp changes the list without leaving
+	// a newer updated_at behind. The ETag covers deletions.
+	versions := httpcache.NewVersions()
+	for _, chirp := range chirps {
+		versions.Add(chirp.ID.String(), chirp.UpdatedAt)
+	}
+	w.Header().Set("Cache-Control", chirpsCacheControl)
+	if httpcache.CheckNotModified(w, r, versions.ETag(), time.Time{}) {
 		return
 	}
 
 	// Convert to JSON response format
 	chirpJSONs := make([]ChirpJSON, len(chirps))
 	for i, chirp := range chirps {
-		chirpJSONs[i] = ChirpJSON{
-			ID:        chirp.ID,
-			Body:      chirp.Body,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			UserID:    chirp.UserID,
-		}
+		chirpJSONs[i] = newChirpJSON(chirp)
+	}
+
+	respondWithJSON(w, http.StatusOK, chirpJSONs)
+}
+
+// listChirps returns every chirp, or every chirp by authorID when it's
+// set, sorted by creation time
+func (cfg *apiConfig) listChirps(ctx context.Context, authorID uuid.NullUUID, desc bool) ([]database.Chirp, *apiError) {
+	var chirps []database.Chirp
+	var err error
+
+	if authorID.Valid {
+		chirps, err = cfg.dbQueries.GetChirpsByAuthorID(ctx, authorID.UUID)
+	} else {
+		chirps, err = cfg.dbQueries.GetChirps(ctx)
+	}
+
+	if err != nil {
+		return nil, &apiError{status: http.StatusInternalServerError, message: "Error getting chirps"}
 	}
 
 	// Sort the chirps based on CreatedAt
-	if sortOrder == "desc" {
-		sort.Slice(chirpJSONs, func(i, j int) bool {
-			return chirpJSONs[i].CreatedAt.After(chirpJSONs[j].CreatedAt)
+	if desc {
+		sort.Slice(chirps, func(i, j int) bool {
+			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
 		})
 	} else {
-		sort.Slice(chirpJSONs, func(i, j int) bool {
-			return chirpJSONs[i].CreatedAt.Before(chirpJSONs[j].CreatedAt)
+		sort.Slice(chirps, func(i, j int) bool {
+			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
 		})
 	}
-
-	respondWithJSON(w, http.StatusOK, chirpJSONs)
+	return chirps, nil
 }
 
 func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
@@ -180,17 +230,16 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := ChirpJSON{
-		ID: chirp.ID,
-		Body: chirp.Body,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		UserID: chirp.UserID,
+	versions := httpcache.NewVersions()
+	versions.Add(chirp.ID.String(), chirp.UpdatedAt)
+	w.Header().Set("Cache-Control", chirpCacheControl)
+	if httpcache.CheckNotModified(w, r, versions.ETag(), versions.LastModified()) {
+		return
 	}
-	respondWithJSON(w, http.StatusOK, response)
+	respondWithJSON(w, http.StatusOK, newChirpJSON(chirp))
 }
 
-func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
 	chirpID := r.PathValue("chirpID")
 	id, err := uuid.Parse(chirpID)
 	if err != nil {
@@ -198,34 +247,58 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT")
+	apiErr := cfg.deleteChirp(r.Context(), userId, id)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
 		return
 	}
-	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	respondWithJSON(w, http.StatusNoContent, map[string]string{"message": "Chirp deleted successfully"})
+
+}
+
+// deleteChirp deletes one of userID's chirps and notifies webhooks and live
+// subscribers
+func (cfg *apiConfig) deleteChirp(ctx context.Context, userId, id uuid.UUID) *apiError {
+	allowed, err := cfg.checkVerifiedFor(ctx, userId, restrictDeleteChirps)
 	if err != nil {
-		respondWithError(w, http.StatusForbidden, "Couldn't validate JWT")
-		return
+		log.Printf("Error: %v\n", err)
+		return &apiError{status: http.StatusInternalServerError, message: "Error checking email verification"}
 	}
-	chirp, err := cfg.dbQueries.GetChirp(r.Context(), id)
+	if !allowed {
+		return &apiError{status: http.StatusForbidden, message: "Verify your email address to delete chirps"}
+	}
+	chirp, err := cfg.dbQueries.GetChirp(ctx, id)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Not found")
-		return
+		return &apiError{status: http.StatusNotFound, message: "Not found"}
 	}
 
-	//check if the user is the owner of the chirp
+	//check if the user is the owner of the chirp, or can moderate it
 	if userId != chirp.UserID {
-		respondWithError(w, http.StatusForbidden, "You are not the owner of this chirp")
-		return
+		user, err := cfg.dbQueries.GetUserByID(ctx, userId)
+		if err != nil || !hasRole(user, roleModerator, roleAdmin) {
+			return &apiError{status: http.StatusForbidden, message: "You are not the owner of this chirp"}
+		}
 	}
 
-	err = cfg.dbQueries.DeleteChirp(r.Context(), id)
+	err = cfg.removeChirp(ctx, chirp)
 	if err != nil {
 		log.Println("Error deleting chirp", err)
-		respondWithError(w, http.StatusNotFound, "Error deleting chirp")
-		return
+		return &apiError{status: http.StatusNotFound, message: "Error deleting chirp"}
 	}
-	respondWithJSON(w, http.StatusNoContent, map[string]string{"message": "Chirp deleted successfully"})
+	return nil
+}
 
+// removeChirp deletes a chirp and notifies webhooks and live subscribers
+func (cfg *apiConfig) removeChirp(ctx context.Context, chirp database.Chirp) error {
+	err := cfg.dbQueries.DeleteChirp(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	deleted := map[string]uuid.UUID{
+		"id":      chirp.ID,
+		"user_id": chirp.UserID,
+	}
+	cfg.queueOutboundWebhook(ctx, chirp.UserID, eventChirpDeleted, deleted)
+	cfg.publishChirpEvent(ctx, eventChirpDeleted, chirp, deleted)
+	return nil
 }
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/grpcserver"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC API only listens on localhost unless GRPC_HOST says otherwise,
+// since without TLS tokens and passwords cross the network in plain text
+const (
+	defaultGRPCHost = "localhost"
+	defaultGRPCPort = "9090"
+)
+
+// serveGRPC serves the gRPC API on addr alongside the HTTP server. It uses
+// TLS when certFile and keyFile are set.
+func (cfg *apiConfig) serveGRPC(addr, certFile, keyFile string) error {
+	opts := []grpc.ServerOption{}
+	if certFile != "" || keyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("error loading gRPC TLS certificate: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	} else if !isLoopbackHost(addr) {
+		log.Printf("Warning: gRPC is served without TLS on %s, set GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE\n", addr)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := grpcserver.New(grpcBackend{cfg: cfg}, cfg.validateGRPCToken, opts...)
+	return server.Serve(listener)
+}
+
+// isLoopbackHost reports whether addr only listens on this machine
+func isLoopbackHost(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateGRPCToken checks tokens for the gRPC API the same way
+// middlewareAuth does for HTTP
+func (cfg *apiConfig) validateGRPCToken(ctx context.Context, token string, scope auth.Scope) (uuid.UUID, error) {
+	userID, apiErr := cfg.authenticateToken(ctx, token, scope)
+	if apiErr != nil {
+		return uuid.Nil, apiErr
+	}
+	return userID, nil
+}
+
+// GRPCStatus reports the error to gRPC callers with the code closest to its
+// HTTP status
+func (e *apiError) GRPCStatus() *status.Status {
+	code := codes.Unknown
+	switch e.status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		code = codes.Internal
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.New(code, e.message)
+}
+
+// grpcBackend runs the gRPC services with the same logic as the HTTP
+// handlers
+type grpcBackend struct {
+	cfg *apiConfig
+}
+
+func (b grpcBackend) CreateUser(ctx context.Context, email, password string) (grpcserver.User, error) {
+	user, apiErr := b.cfg.createUser(ctx, email, password)
+	if apiErr != nil {
+		return grpcserver.User{}, apiErr
+	}
+	return b.user(ctx, user), nil
+}
+
+func (b grpcBackend) GetUser(ctx context.Context, id uuid.UUID) (grpcserver.User, error) {
+	user, err := b.cfg.dbQueries.GetUserByID(ctx, id)
+	if err != nil {
+		return grpcserver.User{}, &apiError{status: http.StatusNotFound, message: "User not found"}
+	}
+	return b.user(ctx, user), nil
+}
+
+func (b grpcBackend) user(ctx context.Context, user database.User) grpcserver.User {
+	return grpcserver.User{
+		ID:              user.ID,
+		Email:           user.Email,
+		CreatedAt:       user.CreatedAt.Time,
+		UpdatedAt:       user.UpdatedAt.Time,
+		IsChirpyRed:     b.cfg.isChirpyRed(ctx, user.ID),
+		IsEmailVerified: user.EmailVerifiedAt.Valid,
+	}
+}
+
+func (b grpcBackend) Login(ctx context.Context, email, password, ip string) (grpcserver.Session, error) {
+	user, mfaToken, apiErr := b.cfg.login(ctx, email, password, ip)
+	if apiErr != nil {
+		return grpcserver.Session{}, apiErr
+	}
+	if mfaToken != "" {
+		return grpcserver.Session{MFAToken: mfaToken}, nil
+	}
+	return b.session(ctx, user)
+}
+
+func (b grpcBackend) LoginMFA(ctx context.Context, mfaToken, code, ip string) (grpcserver.Session, error) {
+	user, apiErr := b.cfg.loginMFA(ctx, mfaToken, code, ip)
+	if apiErr != nil {
+		return grpcserver.Session{}, apiErr
+	}
+	return b.session(ctx, user)
+}
+
+// session issues an access and refresh token pair for a user who has fully
+// authenticated
+func (b grpcBackend) session(ctx context.Context, user database.User) (grpcserver.Session, error) {
+	session, apiErr := b.cfg.newSession(ctx, user)
+	if apiErr != nil {
+		return grpcserver.Session{}, apiErr
+	}
+	return grpcserver.Session{
+		User: grpcserver.User{
+			ID:              session.ID,
+			Email:           session.Email,
+			CreatedAt:       session.CreatedAt,
+			UpdatedAt:       session.UpdatedAt,
+			IsChirpyRed:     session.IsChirpyRed,
+			IsEmailVerified: session.IsEmailVerified,
+		},
+		AccessToken:  session.Token,
+		RefreshToken: session.RefreshToken,
+	}, nil
+}
+
+func (b grpcBackend) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
+	accessToken, apiErr := b.cfg.refreshAccessToken(ctx, refreshToken)
+	if apiErr != nil {
+		return "", apiErr
+	}
+	return accessToken, nil
+}
+
+func (b grpcBackend) RevokeToken(ctx context.Context, refreshToken string) error {
+	return b.cfg.dbQueries.RevokeRefreshToken(ctx, refreshToken)
+}
+
+func (b grpcBackend) CreateChirp(ctx context.Context, userID uuid.UUID, body string) (grpcserver.Chirp, error) {
+	chirp, apiErr := b.cfg.createChirp(ctx, userID, body)
+	if apiErr != nil {
+		return grpcserver.Chirp{}, apiErr
+	}
+	return grpcChirp(newChirpJSON(chirp)), nil
+}
+
+func (b grpcBackend) GetChirp(ctx context.Context, id uuid.UUID) (grpcserver.Chirp, error) {
+	chirp, err := b.cfg.dbQueries.GetChirp(ctx, id)
+	if err != nil {
+		return grpcserver.Chirp{}, &apiError{status: http.StatusNotFound, message: "Error getting chirp"}
+	}
+	return grpcChirp(newChirpJSON(chirp)), nil
+}
+
+func (b grpcBackend) ListChirps(ctx context.Context, authorID uuid.NullUUID, desc bool) ([]grpcserver.Chirp, error) {
+	chirps, apiErr := b.cfg.listChirps(ctx, authorID, desc)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	result := make([]grpcserver.Chirp, len(chirps))
+	for i, chirp := range chirps {
+		result[i] = grpcChirp(newChirpJSON(chirp))
+	}
+	return result, nil
+}
+
+func (b grpcBackend) DeleteChirp(ctx context.Context, userID, id uuid.UUID) error {
+	apiErr := b.cfg.deleteChirp(ctx, userID, id)
+	if apiErr != nil {
+		return apiErr
+	}
+	return nil
+}
+
+func (b grpcBackend) WatchChirps(ctx context.Context, authorID uuid.NullUUID, send func(grpcserver.Chirp) error) error {
+	sub := b.cfg.hub.Subscribe(func(msg pubsub.Message) bool {
+		return isChirpEvent(msg) && msg.Type == eventChirpCreated &&
+			(!authorID.Valid || msg.AuthorID == authorID.UUID)
+	}, 0)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-sub.C():
+			if !ok {
+				// Dropped for falling behind, like SSE clients
+				return &apiError{status: http.StatusServiceUnavailable, message: "Stream fell behind, reconnect"}
+			}
+			chirp := ChirpJSON{}
+			err := json.Unmarshal(msg.Data, &chirp)
+			if err != nil {
+				log.Printf("Error: %v\n", err)
+				continue
+			}
+			err = send(grpcChirp(chirp))
+			if err != nil {
+				// The client went away
+				return nil
+			}
+		}
+	}
+}
+
+func grpcChirp(chirp ChirpJSON) grpcserver.Chirp {
+	return grpcserver.Chirp{
+		ID:        chirp.ID,
+		UserID:    chirp.UserID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+	}
+}
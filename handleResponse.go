@@ -3,7 +3,10 @@ package main
 import (
 	"encoding/json"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string) {
@@ -23,4 +26,22 @@ func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(payload)
-}
\ No newline at end of file
+}
+// apiError is a failure in logic shared by the HTTP and gRPC APIs. status is
+// the HTTP status it's reported with, gRPC maps it to a code.
+type apiError struct {
+	status     int
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func respondWithAPIError(w http.ResponseWriter, err *apiError) {
+	if err.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.retryAfter.Seconds()))))
+	}
+	respondWithError(w, err.status, err.message)
+}
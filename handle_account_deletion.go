@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/mailer"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAccountDeletionGracePeriod = 30 * 24 * time.Hour
+	accountDeletionInterval           = 15 * time.Minute
+	accountDeletionBatchSize          = 50
+)
+
+// What happens to a deleted account's chirps
+const (
+	// deletedChirpsDelete deletes them with the account
+	deletedChirpsDelete = "delete"
+	// deletedChirpsAnonymize keeps them, attributed to a user with no email,
+	// password or anything else that identifies them
+	deletedChirpsAnonymize = "anonymize"
+)
+
+// loadAccountDeletionSettings reads the grace period and what happens to
+// deleted users' chirps
+func loadAccountDeletionSettings() (time.Duration, string, error) {
+	gracePeriod := defaultAccountDeletionGracePeriod
+	if value := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return 0, "", fmt.Errorf("invalid ACCOUNT_DELETION_GRACE_PERIOD")
+		}
+		gracePeriod = parsed
+	}
+	chirps := os.Getenv("ACCOUNT_DELETION_CHIRPS")
+	switch chirps {
+	case "":
+		chirps = deletedChirpsDelete
+	case deletedChirpsDelete, deletedChirpsAnonymize:
+	default:
+		return 0, "", fmt.Errorf("ACCOUNT_DELETION_CHIRPS must be delete or anonymize")
+	}
+	return gracePeriod, chirps, nil
+}
+
+type AccountDeletionJSON struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+// handlerDeleteAccount schedules the caller's account for deletion once
+// they've confirmed their password, and their second factor when it's
+// enabled. Every session is signed out, logging in again before the grace
+// period ends cancels the deletion.
+func (cfg *apiConfig) handlerDeleteAccount(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type request struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	req := request{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	apiErr := cfg.reauthenticate(r.Context(), cfg.clientIP(r), user, req.Password, req.Code)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	if user.DeleteAfter.Valid {
+		respondWithJSON(w, http.StatusAccepted, AccountDeletionJSON{DeleteAfter: user.DeleteAfter.Time})
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	user, err = qtx.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		ID:          userID,
+		DeleteAfter: sql.NullTime{Time: time.Now().Add(cfg.accountDeletionGracePeriod), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account")
+		return
+	}
+	// Sign out every session, logging in again is how the deletion is
+	// cancelled
+	_, err = qtx.RevokeAllRefreshTokensForUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account")
+		return
+	}
+	err = qtx.RevokeAllPersonalAccessTokensForUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account")
+		return
+	}
+
+	go cfg.sendAccountDeletionEmail(user.Email,
+		"Your Chirpy account will be deleted",
+		fmt.Sprintf(
+			"Your Chirpy account is scheduled for deletion on %s, and you've been signed out everywhere.\n\n"+
+				"To keep your account, log in before then.\n",
+			user.DeleteAfter.Time.UTC().Format(time.RFC1123),
+		),
+	)
+	respondWithJSON(w, http.StatusAccepted, AccountDeletionJSON{DeleteAfter: user.DeleteAfter.Time})
+}
+
+// cancelAccountDeletion keeps the account of a user who logged in during
+// their deletion grace period
+func (cfg *apiConfig) cancelAccountDeletion(ctx context.Context, user database.User) {
+	if !user.DeleteAfter.Valid {
+		return
+	}
+	cancelled, err := cfg.dbQueries.CancelUserDeletion(ctx, user.ID)
+	if err != nil {
+		log.Printf("Error cancelling account deletion: %v\n", err)
+		return
+	}
+	if cancelled == 0 {
+		return
+	}
+	go cfg.sendAccountDeletionEmail(user.Email,
+		"Your Chirpy account won't be deleted",
+		"You logged in to your Chirpy account, so it's no longer scheduled for deletion.\n\n"+
+			"If this wasn't you, reset your password.\n",
+	)
+}
+
+func (cfg *apiConfig) sendAccountDeletionEmail(email, subject, body string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: subject,
+		Body:    body,
+	})
+	if err != nil {
+		log.Printf("Error sending account deletion email: %v\n", err)
+	}
+}
+
+// runAccountDeletions deletes accounts whose grace period is over, every
+// interval until ctx is done
+func (cfg *apiConfig) runAccountDeletions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		userIDs, err := cfg.dbQueries.ListUsersDueForDeletion(ctx, accountDeletionBatchSize)
+		if err != nil {
+			log.Printf("Error listing accounts to delete: %v\n", err)
+		}
+		for _, userID := range userIDs {
+			err := cfg.deleteAccount(ctx, userID)
+			if err != nil {
+				log.Printf("Error deleting account %s: %v\n", userID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteAccount deletes a user whose grace period is over, along with
+// every token and everything else that references them. Their chirps are
+// deleted or anonymized according to ACCOUNT_DELETION_CHIRPS.
+func (cfg *apiConfig) deleteAccount(ctx context.Context, userID uuid.UUID) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	user, err := qtx.LockUserForDeletion(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var deletedChirps []database.Chirp
+	if cfg.accountDeletionChirps == deletedChirpsDelete {
+		deletedChirps, err = qtx.GetChirpsByAuthorID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		// Everything else references the user with ON DELETE CASCADE
+		err = qtx.DeleteUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = qtx.DeleteUserData(ctx, userID)
+		if err != nil {
+			return err
+		}
+		err = qtx.AnonymizeUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	cfg.clearLoginFailures(ctx, cfg.loginThrottles("", user.Email))
+	for _, chirp := range deletedChirps {
+		cfg.publishChirpEvent(ctx, eventChirpDeleted, chirp, map[string]uuid.UUID{
+			"id":      chirp.ID,
+			"user_id": chirp.UserID,
+		})
+	}
+	log.Printf("Deleted account %s\n", userID)
+	go cfg.sendAccountDeletionEmail(user.Email,
+		"Your Chirpy account was deleted",
+		"Your Chirpy account and its data have been deleted, as you asked.\n",
+	)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWebhookEventsLimit = 50
+	maxWebhookEventsLimit     = 500
+)
+
+type WebhookEventJSON struct {
+	ID          uuid.UUID       `json:"id"`
+	Provider    string          `json:"provider"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int32           `json:"attempts"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+func webhookEventJSON(event database.WebhookEvent) WebhookEventJSON {
+	resp := WebhookEventJSON{
+		ID:         event.ID,
+		Provider:   event.Provider,
+		EventID:    event.EventID,
+		EventType:  event.EventType,
+		Payload:    json.RawMessage(event.Payload),
+		Status:     event.Status,
+		Error:      event.Error.String,
+		Attempts:   event.Attempts,
+		ReceivedAt: event.ReceivedAt,
+	}
+	if event.ProcessedAt.Valid {
+		resp.ProcessedAt = &event.ProcessedAt.Time
+	}
+	if !json.Valid(event.Payload) {
+		// Stored as received, a body that isn't JSON is shown as a string
+		resp.Payload, _ = json.Marshal(string(event.Payload))
+	}
+	return resp
+}
+
+func (cfg *apiConfig) handlerListWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", webhookStatusProcessing, webhookStatusProcessed, webhookStatusIgnored, webhookStatusFailed:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	limit := defaultWebhookEventsLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > maxWebhookEventsLimit {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := cfg.dbQueries.ListWebhookEvents(r.Context(), database.ListWebhookEventsParams{
+		Limit:  int32(limit),
+		Status: status,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting webhook events")
+		return
+	}
+	resp := make([]WebhookEventJSON, 0, len(events))
+	for _, event := range events {
+		resp = append(resp, webhookEventJSON(event))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerReplayWebhookEvent processes a failed or abandoned event again
+// from its stored payload
+func (cfg *apiConfig) handlerReplayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+	event, err := cfg.dbQueries.GetWebhookEvent(r.Context(), eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Webhook event not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting webhook event")
+		return
+	}
+
+	event, err = cfg.dbQueries.ClaimWebhookEvent(r.Context(), database.ClaimWebhookEventParams{
+		ID:          event.ID,
+		StaleBefore: time.Now().Add(-webhookEventLease),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Only failed events, or events stuck processing, can be replayed")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error claiming webhook event")
+		return
+	}
+
+	event, err = cfg.processWebhookEvent(r.Context(), event)
+	if err != nil {
+		log.Printf("Error replaying webhook event %s: %v\n", event.EventID, err)
+	}
+	// The outcome, failed or not, is part of the returned event
+	respondWithJSON(w, http.StatusOK, webhookEventJSON(event))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/RodolfoCamposGlz/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+type EntitlementsResponse struct {
+	Plan string `json:"plan"`
+	entitlements.Entitlements
+}
+
+// loadEntitlements reads ENTITLEMENTS_FILE, falling back to
+// entitlements.DefaultConfig
+func loadEntitlements() (*entitlements.Service, error) {
+	if path := os.Getenv("ENTITLEMENTS_FILE"); path != "" {
+		return entitlements.Load(path)
+	}
+	return entitlements.New(entitlements.DefaultConfig)
+}
+
+// userEntitlements returns the plan the user is on right now and what it
+// grants
+func (cfg *apiConfig) userEntitlements(ctx context.Context, userID uuid.UUID) (string, entitlements.Entitlements, error) {
+	plan, err := cfg.dbQueries.GetActivePlan(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		plan = entitlements.PlanFree
+	} else if err != nil {
+		return "", entitlements.Entitlements{}, err
+	}
+	return plan, cfg.entitlements.For(plan), nil
+}
+
+func (cfg *apiConfig) handlerGetEntitlements(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	plan, limits, err := cfg.userEntitlements(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting entitlements")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, EntitlementsResponse{
+		Plan:         plan,
+		Entitlements: limits,
+	})
+}
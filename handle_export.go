@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/export"
+	"github.com/google/uuid"
+)
+
+const (
+	dataExportInterval  = 10 * time.Second
+	dataExportBatchSize = 5
+	// dataExportLease is how long a server has to build an export before
+	// another one may try
+	dataExportLease = 10 * time.Minute
+	// dataExportRetention is how long a finished archive can be downloaded
+	dataExportRetention = 7 * 24 * time.Hour
+	// dataExportLinkTTL is how long a download link works. Polling the
+	// export again returns a fresh one.
+	dataExportLinkTTL = 15 * time.Minute
+)
+
+// dataExportStatusReady exports can be downloaded. The others are pending,
+// failed, and expired once the archive has been deleted.
+const dataExportStatusReady = "ready"
+
+// eventDataExportReady notifies a user that their export finished, whether
+// or not it succeeded
+const eventDataExportReady = "export.ready"
+
+type DataExportJSON struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	// ExpiresAt is when the archive is deleted
+	ExpiresAt *time.Time `json:"expires_at"`
+	// DownloadURL is a signed link to the archive that works without an
+	// access token until DownloadURLExpiresAt
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+func newDataExportJSON(dataExport database.GetDataExportRow) DataExportJSON {
+	response := DataExportJSON{
+		ID:        dataExport.ID,
+		Status:    dataExport.Status,
+		Error:     dataExport.Error.String,
+		CreatedAt: dataExport.CreatedAt,
+	}
+	if dataExport.CompletedAt.Valid {
+		response.CompletedAt = &dataExport.CompletedAt.Time
+	}
+	if dataExport.ExpiresAt.Valid {
+		response.ExpiresAt = &dataExport.ExpiresAt.Time
+	}
+	return response
+}
+
+// handlerCreateExport starts bundling the caller's data into an archive.
+// A request while an export is still pending returns that export.
+func (cfg *apiConfig) handlerCreateExport(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	pending, err := cfg.dbQueries.GetPendingDataExport(r.Context(), userID)
+	if err == nil {
+		cfg.respondWithDataExport(w, http.StatusAccepted, database.GetDataExportRow(pending))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating export")
+		return
+	}
+
+	dataExport, err := cfg.dbQueries.CreateDataExport(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another request started one since we looked
+		respondWithError(w, http.StatusConflict, "An export is already running, wait for it to finish")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating export")
+		return
+	}
+	cfg.respondWithDataExport(w, http.StatusAccepted, database.GetDataExportRow(dataExport))
+}
+
+func (cfg *apiConfig) respondWithDataExport(w http.ResponseWriter, code int, dataExport database.GetDataExportRow) {
+	response := newDataExportJSON(dataExport)
+	if dataExport.Status == dataExportStatusReady {
+		linkExpiresAt := time.Now().Add(dataExportLinkTTL)
+		if dataExport.ExpiresAt.Time.Before(linkExpiresAt) {
+			linkExpiresAt = dataExport.ExpiresAt.Time
+		}
+		token, err := cfg.keyring.MakeDownloadToken(dataExport.ID, time.Until(linkExpiresAt))
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create download link")
+			return
+		}
+		response.DownloadURL = cfg.baseURL + "/api/me/export/" + dataExport.ID.String() + "?" + url.Values{"token": {token}}.Encode()
+		response.DownloadURLExpiresAt = &linkExpiresAt
+	}
+	w.Header().Set("Location", "/api/me/export/"+dataExport.ID.String())
+	respondWithJSON(w, code, response)
+}
+
+// handlerGetExport returns an export's status to its owner, or the archive
+// itself when called with a download link's token
+func (cfg *apiConfig) handlerGetExport(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("token") {
+		cfg.downloadExport(w, r)
+		return
+	}
+	cfg.middlewareSessionAuth(cfg.handlerGetExportStatus)(w, r)
+}
+
+func (cfg *apiConfig) handlerGetExportStatus(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID")
+		return
+	}
+	dataExport, err := cfg.dbQueries.GetDataExport(r.Context(), exportID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && dataExport.UserID != userID) {
+		respondWithError(w, http.StatusNotFound, "Export not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting export")
+		return
+	}
+	cfg.respondWithDataExport(w, http.StatusOK, dataExport)
+}
+
+func (cfg *apiConfig) downloadExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID")
+		return
+	}
+	fileID, err := cfg.keyring.ValidateDownloadToken(r.URL.Query().Get("token"))
+	if err != nil || fileID != exportID {
+		respondWithError(w, http.StatusForbidden, "Invalid or expired download link")
+		return
+	}
+	archive, err := cfg.dbQueries.GetDataExportArchive(r.Context(), exportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Export not found or expired")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting export")
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export-`+exportID.String()+`.zip"`)
+	// The link is a credential, don't let shared caches keep the archive
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// runDataExports builds pending exports and expires old archives every
+// interval until ctx is done
+func (cfg *apiConfig) runDataExports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := cfg.dbQueries.ExpireDataExports(ctx)
+		if err != nil {
+			log.Printf("Error expiring data exports: %v\n", err)
+		} else if expired > 0 {
+			log.Printf("Expired %d data exports\n", expired)
+		}
+		cfg.buildDueDataExports(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) buildDueDataExports(ctx context.Context) {
+	exports, err := cfg.dbQueries.ClaimDueDataExports(ctx, database.ClaimDueDataExportsParams{
+		Limit:      dataExportBatchSize,
+		LeaseUntil: time.Now().Add(dataExportLease),
+	})
+	if err != nil {
+		log.Printf("Error claiming data exports: %v\n", err)
+		return
+	}
+	for _, dataExport := range exports {
+		cfg.buildDataExport(ctx, dataExport.ID, dataExport.UserID)
+	}
+}
+
+func (cfg *apiConfig) buildDataExport(ctx context.Context, exportID, userID uuid.UUID) {
+	archive, err := cfg.collectUserData(ctx, userID)
+	buf := &bytes.Buffer{}
+	if err == nil {
+		err = export.Write(buf, archive)
+	}
+	if err != nil {
+		log.Printf("Error building data export %s: %v\n", exportID, err)
+		err = cfg.dbQueries.FailDataExport(ctx, database.FailDataExportParams{
+			ID:    exportID,
+			Error: sql.NullString{String: "Couldn't build the export, request a new one", Valid: true},
+		})
+	} else {
+		err = cfg.dbQueries.CompleteDataExport(ctx, database.CompleteDataExportParams{
+			ID:        exportID,
+			Archive:   buf.Bytes(),
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(dataExportRetention), Valid: true},
+		})
+	}
+	if err != nil {
+		log.Printf("Error recording data export %s: %v\n", exportID, err)
+		return
+	}
+
+	dataExport, err := cfg.dbQueries.GetDataExport(ctx, exportID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+	cfg.publishNotification(ctx, userID, eventDataExportReady, newDataExportJSON(dataExport))
+}
+
+// collectUserData gathers everything stored about a user
+func (cfg *apiConfig) collectUserData(ctx context.Context, userID uuid.UUID) (export.Archive, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+	archive := export.Archive{
+		ExportedAt: time.Now().UTC(),
+		Profile: export.Profile{
+			ID:               user.ID,
+			Email:            user.Email,
+			CreatedAt:        user.CreatedAt.Time,
+			UpdatedAt:        user.UpdatedAt.Time,
+			EmailVerifiedAt:  nullTime(user.EmailVerifiedAt),
+			TwoFactorEnabled: user.TotpEnabledAt.Valid,
+			IsChirpyRed:      cfg.isChirpyRed(ctx, userID),
+			Roles:            user.Roles,
+		},
+		Chirps:               []export.Chirp{},
+		Sessions:             []export.Session{},
+		PersonalAccessTokens: []export.PersonalAccessToken{},
+		Webhooks:             []export.Webhook{},
+		SubscriptionHistory:  []export.SubscriptionEvent{},
+	}
+	if archive.Profile.Roles == nil {
+		archive.Profile.Roles = []string{}
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsByAuthorID(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+	sort.Slice(chirps, func(i, j int) bool {
+		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+	})
+	for _, chirp := range chirps {
+		archive.Chirps = append(archive.Chirps, export.Chirp{
+			ID:        chirp.ID,
+			Body:      chirp.Body,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+		})
+	}
+
+	sessions, err := cfg.dbQueries.ListRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+	for _, session := range sessions {
+		archive.Sessions = append(archive.Sessions, export.Session{
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+			RevokedAt: nullTime(session.RevokedAt),
+		})
+	}
+
+	tokens, err := cfg.dbQueries.ListAllPersonalAccessTokens(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+	for _, token := range tokens {
+		archive.PersonalAccessTokens = append(archive.PersonalAccessTokens, export.PersonalAccessToken{
+			ID:         token.ID,
+			Name:       token.Name,
+			Scopes:     token.Scopes,
+			CreatedAt:  token.CreatedAt,
+			ExpiresAt:  nullTime(token.ExpiresAt),
+			LastUsedAt: nullTime(token.LastUsedAt),
+			RevokedAt:  nullTime(token.RevokedAt),
+		})
+	}
+
+	webhooks, err := cfg.dbQueries.ListWebhookSubscriptions(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+	for _, webhook := range webhooks {
+		archive.Webhooks = append(archive.Webhooks, export.Webhook{
+			ID:        webhook.ID,
+			URL:       webhook.Url,
+			Events:    webhook.Events,
+			CreatedAt: webhook.CreatedAt,
+		})
+	}
+
+	history, err := cfg.dbQueries.ListSubscriptionHistoryForUser(ctx, userID)
+	if err != nil {
+		return export.Archive{}, err
+	}
+	for _, event := range history {
+		archive.SubscriptionHistory = append(archive.SubscriptionHistory, export.SubscriptionEvent{
+			Event:            event.Event,
+			Plan:             event.Plan,
+			Status:           event.Status,
+			CurrentPeriodEnd: event.CurrentPeriodEnd,
+			CreatedAt:        event.CreatedAt,
+		})
+	}
+	return archive, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
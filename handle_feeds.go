@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/feed"
+	"github.com/RodolfoCamposGlz/internal/httpcache"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const (
+	feedMaxChirps = 50
+	// feedTitleLength is how much of a chirp is used as its entry title
+	feedTitleLength = 60
+	// feedCacheControl lets feed readers and proxies reuse a feed briefly,
+	// they revalidate with the ETag afterwards
+	feedCacheControl = "public, max-age=300"
+)
+
+func (cfg *apiConfig) handlerUserFeedRSS(w http.ResponseWriter, r *http.Request) {
+	cfg.serveUserFeed(w, r, "rss")
+}
+
+func (cfg *apiConfig) handlerUserFeedAtom(w http.ResponseWriter, r *http.Request) {
+	cfg.serveUserFeed(w, r, "atom")
+}
+
+func (cfg *apiConfig) serveUserFeed(w http.ResponseWriter, r *http.Request, format string) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+	chirps, err := cfg.dbQueries.GetRecentChirpsByAuthor(r.Context(), database.GetRecentChirpsByAuthorParams{
+		UserID: userID,
+		Limit:  feedMaxChirps,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+
+	// Users don't have public names, so feeds identify them by ID rather
+	// than leak their email
+	cfg.serveFeed(w, r, format, feed.Feed{
+		ID:       "urn:uuid:" + userID.String(),
+		Title:    "Chirps by " + userID.String(),
+		Subtitle: "The latest chirps by this Chirpy user",
+		Link:     cfg.baseURL + "/api/chirps?author_id=" + userID.String(),
+		Self:     cfg.baseURL + r.URL.Path,
+		Updated:  user.CreatedAt.Time,
+	}, chirps)
+}
+
+func (cfg *apiConfig) handlerHashtagFeedAtom(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	// Only accept what pubsub.Hashtags would find, which also keeps the tag
+	// safe to put in the query's regular expression
+	if tags := pubsub.Hashtags("#" + tag); len(tags) != 1 || tags[0] != tag {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag")
+		return
+	}
+	chirps, err := cfg.dbQueries.GetRecentChirpsByHashtag(r.Context(), database.GetRecentChirpsByHashtagParams{
+		Tag:       tag,
+		MaxChirps: feedMaxChirps,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+
+	cfg.serveFeed(w, r, "atom", feed.Feed{
+		ID:       "tag:chirpy,2025:hashtag:" + tag,
+		Title:    "#" + tag + " on Chirpy",
+		Subtitle: "The latest chirps tagged #" + tag,
+		Link:     cfg.baseURL + "/api/chirps",
+		Self:     cfg.baseURL + r.URL.Path,
+		Updated:  time.Unix(0, 0),
+	}, chirps)
+}
+
+// serveFeed adds chirps to f as entries and writes it in format, answering
+// conditional requests with a 304. f.Updated is only used when there are no
+// chirps.
+func (cfg *apiConfig) serveFeed(w http.ResponseWriter, r *http.Request, format string, f feed.Feed, chirps []database.Chirp) {
+	var newest time.Time
+	for _, chirp := range chirps {
+		f.Entries = append(f.Entries, feed.Entry{
+			ID:        "urn:uuid:" + chirp.ID.String(),
+			Title:     feedEntryTitle(chirp.Body),
+			Link:      cfg.baseURL + "/api/chirps/" + chirp.ID.String(),
+			Author:    chirp.UserID.String(),
+			Content:   chirp.Body,
+			Published: chirp.CreatedAt,
+			Updated:   chirp.UpdatedAt,
+		})
+		if chirp.UpdatedAt.After(newest) {
+			newest = chirp.UpdatedAt
+		}
+	}
+	if !newest.IsZero() {
+		f.Updated = newest
+	}
+
+	var body []byte
+	var err error
+	contentType := feed.AtomContentType
+	if format == "rss" {
+		body, err = f.RSS()
+		contentType = feed.RSSContentType
+	} else {
+		body, err = f.Atom()
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error rendering feed")
+		return
+	}
+
+	// No Last-Modified, deleting a chirp changes the feed without leaving a
+	// newer updated_at behind. The ETag covers deletions.
+	w.Header().Set("Cache-Control", feedCacheControl)
+	httpcache.Write(w, r, contentType, body, time.Time{})
+}
+
+func feedEntryTitle(body string) string {
+	runes := []rune(strings.Join(strings.Fields(body), " "))
+	if len(runes) <= feedTitleLength {
+		return string(runes)
+	}
+	return string(runes[:feedTitleLength-1]) + "…"
+}
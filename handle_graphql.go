@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/gql"
+	"github.com/google/uuid"
+)
+
+// handlerGraphQL runs a GraphQL query. Anonymous callers can read public
+// data, callers that send a token must send a valid one and can query
+// viewer.
+func (cfg *apiConfig) handlerGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" {
+		cfg.serveGraphQL(w, r, uuid.Nil)
+		return
+	}
+	cfg.middlewareAuth(auth.ScopeChirpsRead, cfg.serveGraphQL)(w, r)
+}
+
+func (cfg *apiConfig) serveGraphQL(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	req := gql.Request{}
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			err := json.Unmarshal([]byte(variables), &req.Variables)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid variables")
+				return
+			}
+		}
+	} else {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters")
+			return
+		}
+	}
+	if req.Query == "" {
+		respondWithError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+
+	// Errors in the query are reported in the result, like any other
+	// GraphQL server
+	respondWithJSON(w, http.StatusOK, cfg.graphql.Execute(r.Context(), req, userID))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RodolfoCamposGlz/internal/gql"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	// __typename is answered by the schema, so the server needs no store
+	server, err := gql.NewServer(nil, gql.DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &apiConfig{graphql: server}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", cfg.handlerGraphQL)
+	mux.HandleFunc("GET /graphql", cfg.handlerGraphQL)
+	mux.HandleFunc("POST /api/graphql", cfg.handlerGraphQL)
+	mux.HandleFunc("GET /api/graphql", cfg.handlerGraphQL)
+
+	query := url.Values{"query": {"{ __typename }"}}.Encode()
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "POST", method: http.MethodPost, target: "/graphql", body: `{"query":"{ __typename }"}`, wantStatus: http.StatusOK},
+		{name: "GET", method: http.MethodGet, target: "/graphql?" + query, wantStatus: http.StatusOK},
+		{name: "POST at the old path", method: http.MethodPost, target: "/api/graphql", body: `{"query":"{ __typename }"}`, wantStatus: http.StatusOK},
+		{name: "GET at the old path", method: http.MethodGet, target: "/api/graphql?" + query, wantStatus: http.StatusOK},
+		{name: "Invalid body", method: http.MethodPost, target: "/graphql", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "No query", method: http.MethodGet, target: "/graphql", wantStatus: http.StatusBadRequest},
+		{name: "Invalid variables", method: http.MethodGet, target: "/graphql?" + query + "&variables=nope", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			result := struct {
+				Data struct {
+					Typename string `json:"__typename"`
+				} `json:"data"`
+			}{}
+			err := json.Unmarshal(w.Body.Bytes(), &result)
+			if err != nil || result.Data.Typename != "Query" {
+				t.Errorf("body = %s, want the query's result", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/chirpimport"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	chirpImportInterval  = 10 * time.Second
+	chirpImportBatchSize = 5
+	// chirpImportLease is how long a server has to run an import before
+	// another one may try
+	chirpImportLease = 10 * time.Minute
+	// maxChirpImportBytes is the largest archive that can be uploaded
+	maxChirpImportBytes = 10 << 20
+	// maxChirpImportRowErrors is how many row errors an import keeps, the
+	// rest are only counted
+	maxChirpImportRowErrors = 1000
+	// maxChirpImportsPerDay is how many archives a user can upload in 24
+	// hours. Only one of them can be pending at a time.
+	maxChirpImportsPerDay = 5
+)
+
+// chirpImportStatusPending imports haven't run yet. The others are
+// completed, even when some rows failed, and failed when the archive
+// couldn't be read at all.
+const chirpImportStatusPending = "pending"
+
+// eventChirpImportCompleted notifies a user that their import finished,
+// whether or not it succeeded
+const eventChirpImportCompleted = "import.completed"
+
+type ChirpImportJSON struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+	Format string    `json:"format"`
+	// Error is why an import failed as a whole
+	Error         string `json:"error,omitempty"`
+	TotalRows     int32  `json:"total_rows"`
+	ImportedRows  int32  `json:"imported_rows"`
+	DuplicateRows int32  `json:"duplicate_rows"`
+	FailedRows    int32  `json:"failed_rows"`
+	// Errors explains why rows failed, for the first
+	// maxChirpImportRowErrors of them
+	Errors      []chirpimport.RowError `json:"errors"`
+	CreatedAt   time.Time              `json:"created_at"`
+	CompletedAt *time.Time             `json:"completed_at"`
+}
+
+func newChirpImportJSON(chirpImport database.GetChirpImportRow) ChirpImportJSON {
+	response := ChirpImportJSON{
+		ID:            chirpImport.ID,
+		Status:        chirpImport.Status,
+		Format:        chirpImport.Format,
+		Error:         chirpImport.Error.String,
+		TotalRows:     chirpImport.TotalRows,
+		ImportedRows:  chirpImport.ImportedRows,
+		DuplicateRows: chirpImport.DuplicateRows,
+		FailedRows:    chirpImport.FailedRows,
+		Errors:        []chirpimport.RowError{},
+		CreatedAt:     chirpImport.CreatedAt,
+		CompletedAt:   nullTime(chirpImport.CompletedAt),
+	}
+	err := json.Unmarshal(chirpImport.RowErrors, &response.Errors)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+	}
+	return response
+}
+
+// handlerCreateChirpImport accepts a JSON Lines or CSV archive of chirps
+// and imports it in the background. The Content-Type says which it is.
+func (cfg *apiConfig) handlerCreateChirpImport(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	allowed, err := cfg.checkVerifiedFor(r.Context(), userID, restrictPostChirps)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error checking email verification")
+		return
+	}
+	if !allowed {
+		respondWithError(w, http.StatusForbidden, "Verify your email address to post chirps")
+		return
+	}
+
+	format, ok := chirpimport.FormatForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Send a JSON Lines (application/x-ndjson) or CSV (text/csv) archive")
+		return
+	}
+	archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxChirpImportBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Archive is too large")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error reading archive")
+		return
+	}
+	if len(bytes.TrimSpace(archive)) == 0 {
+		respondWithError(w, http.StatusBadRequest, "Archive is empty")
+		return
+	}
+
+	recent, err := cfg.dbQueries.CountChirpImportsSince(r.Context(), database.CountChirpImportsSinceParams{
+		UserID:    userID,
+		CreatedAt: time.Now().Add(-24 * time.Hour),
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error checking import limit")
+		return
+	}
+	if recent >= maxChirpImportsPerDay {
+		respondWithError(w, http.StatusTooManyRequests, "Too many imports, try again tomorrow")
+		return
+	}
+
+	chirpImport, err := cfg.dbQueries.CreateChirpImport(r.Context(), database.CreateChirpImportParams{
+		UserID:        userID,
+		Format:        format,
+		Archive:       archive,
+		NextAttemptAt: time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "An import is already running, wait for it to finish")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating import")
+		return
+	}
+	w.Header().Set("Location", "/api/me/imports/"+chirpImport.ID.String())
+	respondWithJSON(w, http.StatusAccepted, newChirpImportJSON(database.GetChirpImportRow(chirpImport)))
+}
+
+func (cfg *apiConfig) handlerGetChirpImport(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	importID, err := uuid.Parse(r.PathValue("importID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid import ID")
+		return
+	}
+	chirpImport, err := cfg.dbQueries.GetChirpImport(r.Context(), importID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && chirpImport.UserID != userID) {
+		respondWithError(w, http.StatusNotFound, "Import not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting import")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newChirpImportJSON(chirpImport))
+}
+
+// runChirpImports runs pending imports every interval until ctx is done
+func (cfg *apiConfig) runChirpImports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		imports, err := cfg.dbQueries.ClaimDueChirpImports(ctx, database.ClaimDueChirpImportsParams{
+			Limit:      chirpImportBatchSize,
+			LeaseUntil: time.Now().Add(chirpImportLease),
+		})
+		if err != nil {
+			log.Printf("Error claiming chirp imports: %v\n", err)
+		}
+		for _, importID := range imports {
+			cfg.runChirpImport(ctx, importID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runChirpImport imports a pending archive and tells its owner how it went
+func (cfg *apiConfig) runChirpImport(ctx context.Context, importID uuid.UUID) {
+	ran, err := cfg.importChirps(ctx, importID)
+	if err != nil {
+		log.Printf("Error importing chirps %s: %v\n", importID, err)
+		err = cfg.dbQueries.FailChirpImport(ctx, database.FailChirpImportParams{
+			ID:    importID,
+			Error: sql.NullString{String: "Couldn't import the archive, upload it again", Valid: true},
+		})
+		if err != nil {
+			log.Printf("Error recording chirp import %s: %v\n", importID, err)
+			return
+		}
+		ran = true
+	}
+	if !ran {
+		return
+	}
+
+	chirpImport, err := cfg.dbQueries.GetChirpImport(ctx, importID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+	cfg.publishNotification(ctx, chirpImport.UserID, eventChirpImportCompleted, newChirpImportJSON(chirpImport))
+}
+
+// importChirps adds the chirps in an import's archive that pass the same
+// checks as new chirps, keeping their created_at. Chirps the user already
+// has are skipped, so importing an archive twice is harmless. It returns
+// false when the import had already run.
+func (cfg *apiConfig) importChirps(ctx context.Context, importID uuid.UUID) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	pending, err := qtx.GetPendingChirpImportArchive(ctx, importID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	err = qtx.LockUserForImport(ctx, pending.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, rowErrors, err := chirpimport.Read(bytes.NewReader(pending.Archive), pending.Format)
+	if err != nil {
+		err = qtx.FailChirpImport(ctx, database.FailChirpImportParams{
+			ID:    importID,
+			Error: sql.NullString{String: "Invalid archive: " + err.Error(), Valid: true},
+		})
+		if err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	_, limits, err := cfg.userEntitlements(ctx, pending.UserID)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	var imported, duplicates int32
+	for _, row := range rows {
+		if row.CreatedAt.After(now) {
+			rowErrors = append(rowErrors, chirpimport.RowError{Line: row.Line, Error: "created_at is in the future"})
+			continue
+		}
+		cleanedBody, apiErr := validateChirp(row.Body, limits)
+		if apiErr != nil {
+			rowErrors = append(rowErrors, chirpimport.RowError{Line: row.Line, Error: apiErr.message})
+			continue
+		}
+		_, err := qtx.ImportChirp(ctx, database.ImportChirpParams{
+			CreatedAt: row.CreatedAt,
+			Body:      cleanedBody,
+			UserID:    pending.UserID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			duplicates++
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		imported++
+	}
+
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Line < rowErrors[j].Line
+	})
+	failed := int32(len(rowErrors))
+	if len(rowErrors) > maxChirpImportRowErrors {
+		rowErrors = rowErrors[:maxChirpImportRowErrors]
+	}
+	encodedErrors, err := json.Marshal(rowErrors)
+	if err != nil {
+		return false, err
+	}
+	err = qtx.CompleteChirpImport(ctx, database.CompleteChirpImportParams{
+		ID:            importID,
+		TotalRows:     imported + duplicates + failed,
+		ImportedRows:  imported,
+		DuplicateRows: duplicates,
+		FailedRows:    failed,
+		RowErrors:     encodedErrors,
+	})
+	if err != nil {
+		return false, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+	log.Printf("Imported %d chirps for %s, %d duplicates, %d failed\n", imported, pending.UserID, duplicates, failed)
+	return true, nil
+}
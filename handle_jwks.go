@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+)
+
+// loadKeyring builds the JWT keyring. Without a keys directory every token
+// is signed with JWT_SECRET (HS256). With one, the PEM keys in it are
+// loaded and JWT_SECRET, if still set, is only used to verify tokens that
+// were issued before the switch.
+func loadKeyring(jwtSecret, keysDir, activeKID, retiredKIDs string) (*auth.Keyring, error) {
+	if keysDir == "" {
+		if jwtSecret == "" {
+			return nil, errors.New("JWT_SECRET or JWT_KEYS_DIR must be set")
+		}
+		return auth.NewHMACKeyring(jwtSecret)
+	}
+
+	keyring, err := auth.LoadKeyring(keysDir)
+	if err != nil {
+		return nil, err
+	}
+	if jwtSecret != "" {
+		err = keyring.AddHMAC(auth.SharedSecretKeyID, []byte(jwtSecret))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if activeKID == "" {
+		return nil, errors.New("JWT_ACTIVE_KID must be set when using JWT_KEYS_DIR")
+	}
+	err = keyring.SetActive(activeKID)
+	if err != nil {
+		return nil, err
+	}
+	for _, kid := range strings.Split(retiredKIDs, ",") {
+		kid = strings.TrimSpace(kid)
+		if kid == "" {
+			continue
+		}
+		err = keyring.Retire(kid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keyring, nil
+}
+
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	// Verifiers cache the key set, keep it short so new keys are picked up
+	// well before they become active
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.keyring.JWKS())
+}
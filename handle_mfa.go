@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+	totpIssuer        = "Chirpy"
+)
+
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// loadMFAUser makes sure two-factor support is configured and loads the
+// authenticated user
+func (cfg *apiConfig) loadMFAUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.User, bool) {
+	if cfg.mfaEncryptionKey == nil {
+		respondWithError(w, http.StatusNotImplemented, "Two-factor authentication is not configured")
+		return database.User{}, false
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// verifyTOTPCode checks a TOTP code for a user with a stored secret and
+// makes sure each code is only accepted once
+func (cfg *apiConfig) verifyTOTPCode(ctx context.Context, user database.User, code string) (bool, error) {
+	if !user.TotpSecret.Valid {
+		return false, nil
+	}
+	secret, err := auth.DecryptSecret(user.TotpSecret.String, cfg.mfaEncryptionKey)
+	if err != nil {
+		return false, err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	rows, err := cfg.dbQueries.RecordTOTPStep(ctx, database.RecordTOTPStepParams{
+		TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+		ID:           user.ID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code
+func (cfg *apiConfig) verifySecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	ok, err := cfg.verifyTOTPCode(ctx, user, code)
+	if err != nil || ok {
+		return ok, err
+	}
+	rows, err := cfg.dbQueries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (cfg *apiConfig) handlerEnrollTOTP(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, ok := cfg.loadMFAUser(w, r, userID)
+	if !ok {
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error generating TOTP secret")
+		return
+	}
+	encrypted, err := auth.EncryptSecret(secret, cfg.mfaEncryptionKey)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error generating TOTP secret")
+		return
+	}
+	_, err = cfg.dbQueries.SetUserTOTPSecret(r.Context(), database.SetUserTOTPSecretParams{
+		TotpSecret: sql.NullString{String: encrypted, Valid: true},
+		ID:         user.ID,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error storing TOTP secret")
+		return
+	}
+
+	resp := struct {
+		Secret     string `json:"secret"`
+		OTPAuthURL string `json:"otpauth_url"`
+	}{
+		Secret:     secret,
+		OTPAuthURL: auth.TOTPURL(totpIssuer, user.Email, secret),
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerConfirmTOTP(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, ok := cfg.loadMFAUser(w, r, userID)
+	if !ok {
+		return
+	}
+	type request struct {
+		Code string `json:"code"`
+	}
+	req := request{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "Start enrollment first")
+		return
+	}
+
+	valid, err := cfg.verifyTOTPCode(r.Context(), user, req.Code)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error verifying code")
+		return
+	}
+	if !valid {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error generating recovery codes")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.DeleteRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+	for _, code := range codes {
+		err = qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			CodeHash: auth.HashToken(code),
+			UserID:   user.ID,
+		})
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+			return
+		}
+	}
+	_, err = qtx.EnableUserTOTP(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+
+	// Recovery codes are only ever shown here, they are stored hashed
+	resp := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerDisableTOTP(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, ok := cfg.loadMFAUser(w, r, userID)
+	if !ok {
+		return
+	}
+	type request struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	req := request{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+	// Throttled like logging in, a stolen access token mustn't be enough to
+	// guess the password and code
+	apiErr := cfg.reauthenticate(r.Context(), cfg.clientIP(r), user, req.Password, req.Code)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	err = cfg.dbQueries.DeleteRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication")
+		return
+	}
+	_, err = cfg.dbQueries.DisableUserTOTP(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	req := request{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	user, apiErr := cfg.loginMFA(r.Context(), req.MFAToken, req.Code, cfg.clientIP(r))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cfg.respondWithSession(w, r, user)
+}
+
+// loginMFA exchanges the challenge token from login and a TOTP or recovery
+// code for the user, who can then start a session
+func (cfg *apiConfig) loginMFA(ctx context.Context, mfaToken, code, ip string) (database.User, *apiError) {
+	errInvalidToken := &apiError{status: http.StatusUnauthorized, message: "Invalid or expired MFA token"}
+	userID, err := cfg.keyring.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return database.User{}, errInvalidToken
+	}
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil || !user.TotpEnabledAt.Valid {
+		return database.User{}, errInvalidToken
+	}
+
+	// Codes count against the same throttles as passwords, otherwise six
+	// digits could be guessed within one challenge token's lifetime
+	throttles := cfg.loginThrottles(ip, user.Email)
+	attempt, retryAfter, err := cfg.beginLoginAttempt(ctx, ip, throttles)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return database.User{}, &apiError{status: http.StatusInternalServerError, message: "Error checking login attempts"}
+	}
+	if retryAfter > 0 {
+		return database.User{}, errTooManyAttempts(retryAfter)
+	}
+
+	valid, err := cfg.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return database.User{}, &apiError{status: http.StatusInternalServerError, message: "Error verifying code"}
+	}
+	if !valid {
+		cfg.recordLoginFailure(ctx, attempt, &user)
+		return database.User{}, &apiError{status: http.StatusUnauthorized, message: "Invalid code"}
+	}
+
+	cfg.forgiveLoginAttempt(ctx, attempt)
+	cfg.clearLoginFailures(ctx, throttles)
+	return user, nil
+}
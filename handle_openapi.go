@@ -0,0 +1,21 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/httpcache"
+	"github.com/RodolfoCamposGlz/internal/openapi"
+)
+
+const openAPIPath = "/api/openapi.json"
+
+func (cfg *apiConfig) handlerOpenAPI(w http.ResponseWriter, r *http.Request) {
+	httpcache.Write(w, r, "application/json", openapi.Spec, time.Time{})
+}
+
+func (cfg *apiConfig) handlerDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.DocsHTML(openAPIPath))
+}
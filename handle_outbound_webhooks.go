@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// Chirp events, sent to outbound webhooks and the chirp stream
+const (
+	eventChirpCreated = "chirp.created"
+	eventChirpDeleted = "chirp.deleted"
+)
+
+// outboundWebhookEvents are the events users can subscribe to
+var outboundWebhookEvents = []string{
+	eventChirpCreated,
+	eventChirpDeleted,
+}
+
+// reservedWebhookEvents are names kept for events Chirpy can't send yet
+// because the features behind them don't exist. Chirps can't be liked or
+// replied to, so there are no like or reply events.
+var reservedWebhookEvents = map[string]string{
+	"chirp.liked":   "chirps can't be liked yet",
+	"chirp.replied": "chirps can't be replied to yet",
+}
+
+// Delivery statuses. Deliveries are pending until they succeed or fail
+// webhook.DefaultRetryPolicy.MaxAttempts times, then they are dead.
+const (
+	deliveryStatusPending   = "pending"
+	deliveryStatusDelivered = "delivered"
+	deliveryStatusDead      = "dead"
+)
+
+const (
+	webhookDeliveryInterval  = 5 * time.Second
+	webhookDeliveryBatchSize = 20
+	webhookDeliveryTimeout   = 10 * time.Second
+	// webhookDeliveryLease is how long a claimed delivery is hidden from
+	// other workers, it must outlast webhookDeliveryTimeout
+	webhookDeliveryLease = time.Minute
+	webhookSecretPrefix  = "whsec_"
+)
+
+type WebhookSubscriptionJSON struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret is only set in the response that creates it
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryJSON struct {
+	ID             uuid.UUID       `json:"id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastStatusCode *int32          `json:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// outboundEvent is the body of every delivery
+type outboundEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+func webhookSubscriptionJSON(subscription database.WebhookSubscription) WebhookSubscriptionJSON {
+	return WebhookSubscriptionJSON{
+		ID:        subscription.ID,
+		URL:       subscription.Url,
+		Events:    subscription.Events,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+func webhookDeliveryJSON(delivery database.WebhookDelivery) WebhookDeliveryJSON {
+	resp := WebhookDeliveryJSON{
+		ID:        delivery.ID,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		LastError: delivery.LastError.String,
+		CreatedAt: delivery.CreatedAt,
+	}
+	if delivery.Status == deliveryStatusPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.LastStatusCode.Valid {
+		resp.LastStatusCode = &delivery.LastStatusCode.Int32
+	}
+	if delivery.DeliveredAt.Valid {
+		resp.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return resp
+}
+
+// validateWebhookURL requires an absolute https URL whose host only
+// resolves to public addresses. Plain http and private addresses are
+// allowed in dev so receivers can run locally. The sender checks the
+// address again when it connects.
+func (cfg *apiConfig) validateWebhookURL(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Hostname() == "" {
+		return fmt.Errorf("invalid URL")
+	}
+	if cfg.platform == "dev" {
+		if parsed.Scheme != "https" && parsed.Scheme != "http" {
+			return fmt.Errorf("URL must use https")
+		}
+		return nil
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("URL must use https")
+	}
+	err = webhook.CheckHost(ctx, parsed.Hostname())
+	if errors.Is(err, webhook.ErrForbiddenAddress) {
+		return fmt.Errorf("URL can't point at a loopback, private or link-local address")
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't resolve the URL's host")
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerCreateWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type request struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	req := request{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	err = cfg.validateWebhookURL(r.Context(), req.URL)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Events) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one event is required")
+		return
+	}
+	events := []string{}
+	for _, event := range req.Events {
+		if reason, ok := reservedWebhookEvents[event]; ok {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("event %q isn't available, %s", event, reason))
+			return
+		}
+		if !slices.Contains(outboundWebhookEvents, event) {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("unknown event %q", event))
+			return
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	secret, err := auth.MakeSecureToken()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating webhook")
+		return
+	}
+	secret = webhookSecretPrefix + secret
+	subscription, err := cfg.dbQueries.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		UserID: userID,
+		Url:    req.URL,
+		Events: events,
+		Secret: secret,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating webhook")
+		return
+	}
+
+	resp := webhookSubscriptionJSON(subscription)
+	resp.Secret = secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) handlerListWebhooks(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	subscriptions, err := cfg.dbQueries.ListWebhookSubscriptions(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error listing webhooks")
+		return
+	}
+	resp := make([]WebhookSubscriptionJSON, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		resp = append(resp, webhookSubscriptionJSON(subscription))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerDeleteWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteWebhookSubscription(r.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:     webhookID,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting webhook")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedWebhook returns the subscription in the path if it belongs to
+// userID, responding with an error otherwise
+func (cfg *apiConfig) ownedWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.WebhookSubscription, bool) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return database.WebhookSubscription{}, false
+	}
+	subscription, err := cfg.dbQueries.GetWebhookSubscription(r.Context(), webhookID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && subscription.UserID != userID) {
+		respondWithError(w, http.StatusNotFound, "Webhook not found")
+		return database.WebhookSubscription{}, false
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error getting webhook")
+		return database.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+func (cfg *apiConfig) handlerListWebhookDeliveries(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	subscription, ok := cfg.ownedWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", deliveryStatusPending, deliveryStatusDelivered, deliveryStatusDead:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	limit := 50
+	if param := r.URL.Query().Get("limit"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 1 || parsed > 500 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := cfg.dbQueries.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          int32(limit),
+		Status:         status,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error listing deliveries")
+		return
+	}
+	resp := make([]WebhookDeliveryJSON, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		resp = append(resp, webhookDeliveryJSON(delivery))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerRetryWebhookDelivery gives a dead delivery a fresh set of attempts
+func (cfg *apiConfig) handlerRetryWebhookDelivery(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	subscription, ok := cfg.ownedWebhook(w, r, userID)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+	delivery, err := cfg.dbQueries.RetryDeadWebhookDelivery(r.Context(), database.RetryDeadWebhookDeliveryParams{
+		ID:             deliveryID,
+		SubscriptionID: subscription.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Only dead deliveries can be retried")
+		return
+	}
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error retrying delivery")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, webhookDeliveryJSON(delivery))
+}
+
+// queueOutboundWebhook schedules a delivery of eventType to every webhook of
+// userID that subscribed to it. Failing to queue doesn't fail the action
+// that caused the event.
+func (cfg *apiConfig) queueOutboundWebhook(ctx context.Context, userID uuid.UUID, eventType string, data any) {
+	subscriptions, err := cfg.dbQueries.ListWebhookSubscriptionsForEvent(ctx, database.ListWebhookSubscriptionsForEventParams{
+		UserID: userID,
+		Event:  eventType,
+	})
+	if err != nil {
+		log.Printf("Error queueing %s webhooks: %v\n", eventType, err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	event := outboundEvent{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error queueing %s webhooks: %v\n", eventType, err)
+		return
+	}
+	for _, subscription := range subscriptions {
+		err = cfg.dbQueries.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        payload,
+		})
+		if err != nil {
+			log.Printf("Error queueing %s webhook: %v\n", eventType, err)
+		}
+	}
+}
+
+// runWebhookDeliveries sends due deliveries every interval until ctx is done
+func (cfg *apiConfig) runWebhookDeliveries(ctx context.Context, interval time.Duration) {
+	sender := webhook.NewSender(webhookDeliveryTimeout, cfg.platform == "dev")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cfg.sendDueWebhookDeliveries(ctx, sender)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) sendDueWebhookDeliveries(ctx context.Context, sender *webhook.Sender) {
+	deliveries, err := cfg.dbQueries.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		Limit:      webhookDeliveryBatchSize,
+		LeaseUntil: time.Now().Add(webhookDeliveryLease),
+	})
+	if err != nil {
+		log.Printf("Error claiming webhook deliveries: %v\n", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.sendWebhookDelivery(ctx, sender, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+func (cfg *apiConfig) sendWebhookDelivery(ctx context.Context, sender *webhook.Sender, delivery database.WebhookDelivery) {
+	subscription, err := cfg.dbQueries.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		log.Printf("Error getting webhook for delivery %s: %v\n", delivery.ID, err)
+		return
+	}
+
+	statusCode, sendErr := sender.Send(ctx, webhook.Delivery{
+		ID:        delivery.ID.String(),
+		URL:       subscription.Url,
+		Secret:    []byte(subscription.Secret),
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+	}, time.Now())
+	lastStatusCode := sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0}
+
+	if sendErr == nil {
+		err = cfg.dbQueries.MarkWebhookDeliveryDelivered(ctx, database.MarkWebhookDeliveryDeliveredParams{
+			ID:             delivery.ID,
+			LastStatusCode: lastStatusCode,
+		})
+	} else {
+		status := deliveryStatusPending
+		delay, retry := webhook.DefaultRetryPolicy.NextAttempt(int(delivery.Attempts) + 1)
+		if !retry {
+			status = deliveryStatusDead
+		}
+		err = cfg.dbQueries.RecordWebhookDeliveryFailure(ctx, database.RecordWebhookDeliveryFailureParams{
+			ID:             delivery.ID,
+			Status:         status,
+			NextAttemptAt:  time.Now().Add(delay),
+			LastStatusCode: lastStatusCode,
+			LastError:      sql.NullString{String: sendErr.Error(), Valid: true},
+		})
+	}
+	if err != nil {
+		log.Printf("Error recording webhook delivery %s: %v\n", delivery.ID, err)
+	}
+}
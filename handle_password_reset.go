@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/mailer"
+)
+
+const passwordResetTTL = time.Hour
+
+func (cfg *apiConfig) handlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Email string `json:"email"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	req := request{}
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	// The lookup and email happen in the background so the response is the
+	// same, and takes the same time, whether or not the account exists
+	go cfg.sendPasswordReset(req.Email)
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If an account exists for that email, a password reset link has been sent",
+	})
+}
+
+func (cfg *apiConfig) sendPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := cfg.dbQueries.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error: %v\n", err)
+		}
+		return
+	}
+
+	token, err := auth.MakeSecureToken()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+
+	// Only the most recently requested link should work
+	err = cfg.dbQueries.InvalidatePasswordResetTokens(ctx, user.ID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+	_, err = cfg.dbQueries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", cfg.baseURL, url.QueryEscape(token))
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for your Chirpy account.\n\n"+
+				"Use this token within %v to choose a new password:\n\n%s\n\n%s\n\n"+
+				"If you didn't request this, you can ignore this email.\n",
+			passwordResetTTL, token, link,
+		),
+	})
+	if err != nil {
+		log.Printf("Error sending password reset email: %v\n", err)
+	}
+}
+
+func (cfg *apiConfig) handlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	req := request{}
+	err := decoder.Decode(&req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Token and password are required")
+		return
+	}
+
+	hashedPassword, err := cfg.passwordHasher.Hash(req.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error hashing password")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	resetToken, err := qtx.ConsumePasswordResetToken(r.Context(), auth.HashToken(req.Token))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	_, err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             resetToken.UserID,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	// Sign out every existing session, the old password may be compromised
+	_, err = qtx.RevokeAllRefreshTokensForUser(r.Context(), resetToken.UserID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
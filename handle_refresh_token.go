@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -15,36 +16,43 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshToken, err := cfg.dbQueries.GetRefreshTokenByToken(r.Context(), token)
+	accessToken, apiErr := cfg.refreshAccessToken(r.Context(), token)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	resp := struct {
+		Token string `json:"token"`
+	}{
+		Token: accessToken,
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// refreshAccessToken returns a new access token for a refresh token that is
+// neither expired nor revoked
+func (cfg *apiConfig) refreshAccessToken(ctx context.Context, token string) (string, *apiError) {
+	refreshToken, err := cfg.dbQueries.GetRefreshTokenByToken(ctx, token)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
-		return
+		return "", &apiError{status: http.StatusUnauthorized, message: "Invalid refresh token"}
 	}
 	//verify if the refresh token is expired
 	if refreshToken.ExpiresAt.Before(time.Now()) {
-		respondWithError(w, http.StatusUnauthorized, "Refresh token expired")
-		return
+		return "", &apiError{status: http.StatusUnauthorized, message: "Refresh token expired"}
 	}
 
 	//verify if the refresh token is revoked
 	if refreshToken.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "Refresh token revoked")
-		return
+		return "", &apiError{status: http.StatusUnauthorized, message: "Refresh token revoked"}
 	}
 
 	//create a new access token
-	accessToken, err := auth.MakeJWT(refreshToken.UserID, cfg.jwtSecret, time.Hour)
+	accessToken, err := cfg.keyring.MakeJWT(refreshToken.UserID, time.Hour)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusInternalServerError, "Couldn't create access JWT")
-		return
+		return "", &apiError{status: http.StatusInternalServerError, message: "Couldn't create access JWT"}
 	}
-
-	resp := struct {
-		Token string `json:"token"`
-	}{
-		Token: accessToken,
-	}
-	respondWithJSON(w, http.StatusOK, resp)
+	return accessToken, nil
 }
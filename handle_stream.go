@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const (
+	streamHistory           = 1000
+	streamHeartbeatInterval = 15 * time.Second
+	// streamRetry is how long EventSource clients wait before reconnecting
+	streamRetry = 3 * time.Second
+)
+
+// publishChirpEvent tells stream subscribers about a chirp. Failing to
+// publish doesn't fail the action that caused the event.
+func (cfg *apiConfig) publishChirpEvent(ctx context.Context, eventType string, chirp database.Chirp, data any) {
+	cfg.publish(ctx, cfg.events, pubsub.Message{
+		Type:     eventType,
+		AuthorID: chirp.UserID,
+		ChirpID:  chirp.ID,
+		Hashtags: pubsub.Hashtags(chirp.Body),
+	}, data)
+}
+
+// publishNotification sends an event to a single user's live connections
+func (cfg *apiConfig) publishNotification(ctx context.Context, userID uuid.UUID, eventType string, data any) {
+	cfg.publish(ctx, cfg.events, pubsub.Message{
+		Type:      eventType,
+		Recipient: userID,
+	}, data)
+}
+
+func (cfg *apiConfig) publish(ctx context.Context, publisher pubsub.Publisher, msg pubsub.Message, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error publishing %s: %v\n", msg.Type, err)
+		return
+	}
+	msg.Data = payload
+	err = publisher.Publish(ctx, msg)
+	if err != nil {
+		log.Printf("Error publishing %s: %v\n", msg.Type, err)
+	}
+}
+
+// isChirpEvent reports whether msg is a public chirp.created or
+// chirp.deleted event
+func isChirpEvent(msg pubsub.Message) bool {
+	return msg.Recipient == uuid.Nil && (msg.Type == eventChirpCreated || msg.Type == eventChirpDeleted)
+}
+
+// handlerStreamChirps streams chirp.created and chirp.deleted events as
+// Server-Sent Events
+func (cfg *apiConfig) handlerStreamChirps(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	filters := []func(pubsub.Message) bool{isChirpEvent}
+	if authorID := r.URL.Query().Get("author_id"); authorID != "" {
+		authorUUID, err := uuid.Parse(authorID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+			return
+		}
+		filters = append(filters, func(msg pubsub.Message) bool {
+			return msg.AuthorID == authorUUID
+		})
+	}
+	if hashtag := r.URL.Query().Get("hashtag"); hashtag != "" {
+		hashtag = strings.ToLower(strings.TrimPrefix(hashtag, "#"))
+		filters = append(filters, func(msg pubsub.Message) bool {
+			return slices.Contains(msg.Hashtags, hashtag)
+		})
+	}
+
+	// Browsers send the header when EventSource reconnects, the query
+	// parameter lets clients resume on their first connection
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		after = parsed
+	}
+
+	sub := cfg.hub.Subscribe(func(msg pubsub.Message) bool {
+		for _, filter := range filters {
+			if !filter(msg) {
+				return false
+			}
+		}
+		return true
+	}, after)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.C():
+			if !ok {
+				// Dropped for falling behind, the client resumes from its
+				// last event when it reconnects
+				return
+			}
+			_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, msg.Data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+	"github.com/google/uuid"
+)
+
+const defaultSubscriptionExpiryInterval = 15 * time.Minute
+
+// eventSubscriptionUpdated notifies a user that their subscription changed
+const eventSubscriptionUpdated = "subscription.updated"
+
+type SubscriptionJSON struct {
+	Plan              string    `json:"plan"`
+	Status            string    `json:"status"`
+	CurrentPeriodEnd  time.Time `json:"current_period_end"`
+	CancelAtPeriodEnd bool      `json:"cancel_at_period_end"`
+}
+
+// applySubscriptionEvent moves a user's subscription through event and
+// records the change in its history. webhookEventID links the history row
+// to the webhook that caused it.
+func (cfg *apiConfig) applySubscriptionEvent(ctx context.Context, userID uuid.UUID, event subscriptions.Event, plan string, periodEnd time.Time, webhookEventID uuid.NullUUID) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	var current *subscriptions.State
+	existing, err := qtx.GetSubscriptionByUserID(ctx, userID)
+	if err == nil {
+		current = &subscriptions.State{
+			Plan:              existing.Plan,
+			Status:            subscriptions.Status(existing.Status),
+			CurrentPeriodEnd:  existing.CurrentPeriodEnd,
+			CancelAtPeriodEnd: existing.CancelAtPeriodEnd,
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	next, err := subscriptions.Apply(current, event, plan, periodEnd, time.Now())
+	if err != nil {
+		return err
+	}
+
+	subscription, err := qtx.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		UserID:            userID,
+		Plan:              next.Plan,
+		Status:            string(next.Status),
+		CurrentPeriodEnd:  next.CurrentPeriodEnd,
+		CancelAtPeriodEnd: next.CancelAtPeriodEnd,
+	})
+	if err != nil {
+		return err
+	}
+	err = qtx.CreateSubscriptionHistory(ctx, database.CreateSubscriptionHistoryParams{
+		SubscriptionID:   subscription.ID,
+		Event:            string(event),
+		Plan:             subscription.Plan,
+		Status:           subscription.Status,
+		CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+		WebhookEventID:   webhookEventID,
+	})
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	cfg.publishNotification(ctx, userID, eventSubscriptionUpdated, SubscriptionJSON{
+		Plan:              subscription.Plan,
+		Status:            subscription.Status,
+		CurrentPeriodEnd:  subscription.CurrentPeriodEnd,
+		CancelAtPeriodEnd: subscription.CancelAtPeriodEnd,
+	})
+	return nil
+}
+
+// isChirpyRed reports whether the user has a subscription that is active
+// right now. It doesn't depend on the expiry job having run.
+func (cfg *apiConfig) isChirpyRed(ctx context.Context, userID uuid.UUID) bool {
+	red, err := cfg.dbQueries.IsUserChirpyRed(ctx, userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return false
+	}
+	return red
+}
+
+// runSubscriptionExpiry marks subscriptions whose period ended without a
+// renewal as expired, every interval until ctx is done
+func (cfg *apiConfig) runSubscriptionExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := cfg.dbQueries.ExpireLapsedSubscriptions(ctx)
+		if err != nil {
+			log.Printf("Error expiring subscriptions: %v\n", err)
+		} else if len(expired) > 0 {
+			log.Printf("Expired %d subscriptions\n", len(expired))
+		}
+		for _, subscription := range expired {
+			cfg.publishNotification(ctx, subscription.UserID, eventSubscriptionUpdated, SubscriptionJSON{
+				Plan:             subscription.Plan,
+				Status:           subscription.Status,
+				CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/mailer"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+// Actions that UNVERIFIED_RESTRICTIONS can block until the user confirms
+// their email address
+const (
+	restrictPostChirps   = "post_chirps"
+	restrictDeleteChirps = "delete_chirps"
+)
+
+func parseRestrictions(value string) (map[string]bool, error) {
+	restrictions := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+			continue
+		case restrictPostChirps, restrictDeleteChirps:
+			restrictions[name] = true
+		default:
+			return nil, fmt.Errorf("unknown unverified restriction %q", name)
+		}
+	}
+	return restrictions, nil
+}
+
+// checkVerifiedFor reports whether userID may perform action. It is always
+// true unless the action is restricted for unverified accounts.
+func (cfg *apiConfig) checkVerifiedFor(ctx context.Context, userID uuid.UUID, action string) (bool, error) {
+	if !cfg.unverifiedRestrictions[action] {
+		return true, nil
+	}
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerifiedAt.Valid, nil
+}
+
+// sendEmailVerification emails a confirmation link for email to the user.
+// Any previous link stops working.
+func (cfg *apiConfig) sendEmailVerification(userID uuid.UUID, email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := auth.MakeSecureToken()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+	err = cfg.dbQueries.InvalidateEmailVerificationTokens(ctx, userID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+	_, err = cfg.dbQueries.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+		Email:     email,
+		UserID:    userID,
+	})
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/api/verify-email?token=%s", cfg.baseURL, url.QueryEscape(token))
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your Chirpy email address",
+		Body: fmt.Sprintf(
+			"Confirm this email address for your Chirpy account by opening this link within %v:\n\n%s\n\n"+
+				"If you didn't request this, you can ignore this email.\n",
+			emailVerificationTTL, link,
+		),
+	})
+	if err != nil {
+		log.Printf("Error sending verification email: %v\n", err)
+	}
+}
+
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing token")
+		return
+	}
+
+	// The token is only used up if the email is verified too
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	verification, err := qtx.ConsumeEmailVerificationToken(r.Context(), auth.HashToken(token))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token")
+		return
+	}
+
+	user, err := qtx.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		Email: verification.Email,
+		ID:    verification.UserID,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			respondWithError(w, http.StatusConflict, "Email is already in use")
+			return
+		}
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+
+	response := UserResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		CreatedAt:       user.CreatedAt.Time,
+		UpdatedAt:       user.UpdatedAt.Time,
+		IsChirpyRed:     cfg.isChirpyRed(r.Context(), user.ID),
+		IsEmailVerified: user.EmailVerifiedAt.Valid,
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerResendVerification(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	// Resend to the address awaiting confirmation if there is one, otherwise
+	// to the current address if it was never confirmed
+	email, err := cfg.dbQueries.GetPendingEmailForUser(r.Context(), user.ID)
+	if err != nil {
+		if user.EmailVerifiedAt.Valid {
+			respondWithError(w, http.StatusBadRequest, "Email is already verified")
+			return
+		}
+		email = user.Email
+	}
+
+	go cfg.sendEmailVerification(user.ID, email)
+
+	respondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Verification email sent",
+	})
+}
@@ -1,57 +1,187 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"time"
 
-	"github.com/RodolfoCamposGlz/internal/auth"
 	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/payments"
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
 	"github.com/google/uuid"
 )
 
+const (
+	polkaProvider       = "polka"
+	maxWebhookBodyBytes = 1 << 20
+	// webhookEventLease is how long an event is processing before it's
+	// considered abandoned and can be claimed again
+	webhookEventLease = 5 * time.Minute
+)
+
+// Webhook event statuses. An event is inserted as processing and ends up
+// processed, ignored (an event type we don't handle or a stale event) or
+// failed. Failed events are retried when the provider redelivers them or an
+// admin replays them, and so are events still processing after
+// webhookEventLease, whose server stopped before finishing them.
+const (
+	webhookStatusProcessing = "processing"
+	webhookStatusProcessed  = "processed"
+	webhookStatusIgnored    = "ignored"
+	webhookStatusFailed     = "failed"
+)
+
+var (
+	errWebhookEventIgnored = errors.New("event ignored")
+	errWebhookUserNotFound = errors.New("user not found")
+)
+
+// handlePaymentWebhooks receives webhooks from the payment provider named in
+// the path
+func (cfg *apiConfig) handlePaymentWebhooks(w http.ResponseWriter, r *http.Request) {
+	provider, ok := cfg.paymentProviders[r.PathValue("provider")]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown payment provider")
+		return
+	}
+	cfg.receivePaymentWebhook(w, r, provider)
+}
+
+// handlePolkaWebhooks serves the original Polka webhook URL
 func (cfg *apiConfig) handlePolkaWebhooks(w http.ResponseWriter, r *http.Request) {
-	_, err := auth.GetAPIKey(r.Header, cfg.polkaKey)
+	provider, ok := cfg.paymentProviders[polkaProvider]
+	if !ok {
+		respondWithError(w, http.StatusNotImplemented, "Polka webhooks are not configured")
+		return
+	}
+	cfg.receivePaymentWebhook(w, r, provider)
+}
+
+func (cfg *apiConfig) receivePaymentWebhook(w http.ResponseWriter, r *http.Request, provider payments.Provider) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid API key")
+		respondWithError(w, http.StatusBadRequest, "Error reading request body")
 		return
 	}
-	type requestBody struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+	err = provider.VerifySignature(r.Header, body, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid webhook signature")
+		return
 	}
-	decoder := json.NewDecoder(r.Body)
 
-	var req requestBody
-	err = decoder.Decode(&req)
-	if err != nil {
+	parsed, err := provider.ParseEvent(body)
+	if err != nil && !errors.Is(err, payments.ErrUnsupportedEvent) {
 		log.Println("Error decoding request body", err)
-		respondWithError(w, http.StatusBadRequest, "Error decoding request body")
+		respondWithError(w, http.StatusBadRequest, "Invalid event")
 		return
 	}
 
-	if req.Event != "user.upgraded" {
-		respondWithError(w, http.StatusNoContent, "Event not supported")
-		return
+	event, err := cfg.dbQueries.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+		Provider:  provider.Name(),
+		EventID:   parsed.ID,
+		EventType: parsed.Type,
+		Payload:   body,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// A redelivery. Only events that failed last time, or were abandoned
+		// while processing, are processed again.
+		existing, err := cfg.dbQueries.GetWebhookEventByEventID(r.Context(), database.GetWebhookEventByEventIDParams{
+			Provider: provider.Name(),
+			EventID:  parsed.ID,
+		})
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			respondWithError(w, http.StatusInternalServerError, "Error recording event")
+			return
+		}
+		event, err = cfg.dbQueries.ClaimWebhookEvent(r.Context(), database.ClaimWebhookEventParams{
+			ID:          existing.ID,
+			StaleBefore: time.Now().Add(-webhookEventLease),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 	}
-	userID, err := uuid.Parse(req.Data.UserID)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		log.Printf("Error: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, "Error recording event")
 		return
 	}
 
-	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
-	if err != nil {
+	_, err = cfg.processWebhookEvent(r.Context(), event)
+	switch {
+	case errors.Is(err, errWebhookUserNotFound):
 		respondWithError(w, http.StatusNotFound, "User not found")
-		return
+	case errors.Is(err, subscriptions.ErrNoSubscription):
+		// Possibly delivered before the upgrade, a retry can succeed
+		respondWithError(w, http.StatusConflict, "User has no subscription")
+	case err != nil:
+		log.Printf("Error processing webhook event %s: %v\n", event.EventID, err)
+		respondWithError(w, http.StatusInternalServerError, "Error processing event")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// processWebhookEvent applies a claimed event and records the outcome. The
+// returned error is the processing error, if any.
+func (cfg *apiConfig) processWebhookEvent(ctx context.Context, event database.WebhookEvent) (database.WebhookEvent, error) {
+	status := webhookStatusProcessed
+	errMessage := sql.NullString{}
+	processErr := cfg.applyWebhookEvent(ctx, event)
+	if errors.Is(processErr, errWebhookEventIgnored) {
+		status = webhookStatusIgnored
+		errMessage = sql.NullString{String: processErr.Error(), Valid: true}
+		processErr = nil
+	} else if processErr != nil {
+		status = webhookStatusFailed
+		errMessage = sql.NullString{String: processErr.Error(), Valid: true}
 	}
 
-	cfg.dbQueries.UpdateUserIsChirpyRed(r.Context(), database.UpdateUserIsChirpyRedParams{
-		ID:          user.ID,
-		IsChirpyRed: sql.NullBool{Bool: true, Valid: true},
+	finished, err := cfg.dbQueries.FinishWebhookEvent(ctx, database.FinishWebhookEventParams{
+		ID:     event.ID,
+		Status: status,
+		Error:  errMessage,
 	})
-	respondWithJSON(w, http.StatusNoContent, "User upgraded")
+	if err != nil {
+		return event, fmt.Errorf("error recording outcome: %w", err)
+	}
+	return finished, processErr
+}
+
+// applyWebhookEvent parses a stored event with the provider that sent it
+// and applies it to the user's subscription
+func (cfg *apiConfig) applyWebhookEvent(ctx context.Context, event database.WebhookEvent) error {
+	provider, ok := cfg.paymentProviders[event.Provider]
+	if !ok {
+		return fmt.Errorf("payment provider %q is not configured", event.Provider)
+	}
+	parsed, err := provider.ParseEvent(event.Payload)
+	if errors.Is(err, payments.ErrUnsupportedEvent) {
+		return fmt.Errorf("%w: unsupported event type %q", errWebhookEventIgnored, parsed.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.dbQueries.GetUserByID(ctx, parsed.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errWebhookUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = cfg.applySubscriptionEvent(ctx, parsed.UserID, parsed.Event, parsed.Plan, parsed.CurrentPeriodEnd, uuid.NullUUID{UUID: event.ID, Valid: true})
+	if errors.Is(err, subscriptions.ErrInactive) || errors.Is(err, subscriptions.ErrUnsupportedEvent) {
+		// Stale, such as a payment failure after a downgrade
+		return fmt.Errorf("%w: %w", errWebhookEventIgnored, err)
+	}
+	return err
 }
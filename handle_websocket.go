@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// signalsChannel carries typing and presence pings between instances
+	signalsChannel = "chirpy_signals"
+
+	wsWriteTimeout    = 10 * time.Second
+	wsPongTimeout     = 60 * time.Second
+	wsPingInterval    = 30 * time.Second
+	wsSendBuffer      = 64
+	wsMaxMessageBytes = 4096
+	wsMaxChannels     = 50
+	// wsCloseTokenExpired is in the close code range reserved for
+	// applications
+	wsCloseTokenExpired = 4001
+)
+
+// Ephemeral events clients send to a chirp thread
+const (
+	eventTyping   = "typing"
+	eventPresence = "presence"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Connections authenticate with a bearer token rather than cookies, so
+	// another site can't open one on a user's behalf
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type wsClientMessage struct {
+	Type        string `json:"type"`
+	Channel     string `json:"channel"`
+	LastEventID int64  `json:"last_event_id"`
+	Token       string `json:"token"`
+}
+
+type wsServerMessage struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	ID      int64           `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// wsChannel is a client's subscription to a channel, which can span both
+// hubs
+type wsChannel struct {
+	subs []*pubsub.Subscription
+	// closed is set before the client unsubscribes, so that a subscription
+	// closing on its own can be told apart
+	closed atomic.Bool
+}
+
+type wsConn struct {
+	ctx      context.Context
+	cfg      *apiConfig
+	conn     *websocket.Conn
+	userID   uuid.UUID
+	send     chan wsServerMessage
+	done     chan struct{}
+	closing  sync.Once
+	expiry   *time.Timer
+	channels map[string]*wsChannel
+}
+
+// handlerWebSocket upgrades to a WebSocket that streams channels the client
+// subscribes to. The access JWT comes from the Authorization header, or the
+// access_token query parameter for clients that can't set headers. The
+// connection is closed when the token expires unless the client sends a
+// fresh one.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT")
+		return
+	}
+	userID, expiresAt, err := cfg.keyring.ValidateJWTExpiry(token)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid JWT")
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded
+		return
+	}
+	c := &wsConn{
+		ctx:      r.Context(),
+		cfg:      cfg,
+		conn:     conn,
+		userID:   userID,
+		send:     make(chan wsServerMessage, wsSendBuffer),
+		done:     make(chan struct{}),
+		channels: map[string]*wsChannel{},
+	}
+	c.expiry = time.AfterFunc(time.Until(expiresAt), func() {
+		c.close(wsCloseTokenExpired, "token expired")
+	})
+
+	go c.writeLoop()
+	c.readLoop()
+
+	c.expiry.Stop()
+	for _, channel := range c.channels {
+		channel.close()
+	}
+	c.close(websocket.CloseNormalClosure, "")
+}
+
+// close sends a close frame and shuts the connection down, which ends both
+// loops. Only the first call has an effect.
+func (c *wsConn) close(code int, reason string) {
+	c.closing.Do(func() {
+		close(c.done)
+		deadline := time.Now().Add(wsWriteTimeout)
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+		c.conn.Close()
+	})
+}
+
+// queue sends msg to the client, closing connections that fall too far
+// behind
+func (c *wsConn) queue(msg wsServerMessage) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	default:
+		c.close(websocket.CloseTryAgainLater, "too slow")
+	}
+}
+
+func (c *wsConn) queueError(format string, args ...any) {
+	c.queue(wsServerMessage{Type: "error", Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err := c.conn.WriteJSON(msg)
+			if err != nil {
+				c.close(websocket.CloseInternalServerErr, "")
+				return
+			}
+		case <-ticker.C:
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+			if err != nil {
+				c.close(websocket.CloseInternalServerErr, "")
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) readLoop() {
+	c.conn.SetReadLimit(wsMaxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := wsClientMessage{}
+		err = json.Unmarshal(data, &msg)
+		if err != nil {
+			c.queueError("invalid JSON")
+			continue
+		}
+		c.handle(msg)
+	}
+}
+
+func (c *wsConn) handle(msg wsClientMessage) {
+	switch msg.Type {
+	case "subscribe":
+		c.subscribe(msg.Channel, msg.LastEventID)
+	case "unsubscribe":
+		if channel, ok := c.channels[msg.Channel]; ok {
+			channel.close()
+			delete(c.channels, msg.Channel)
+		}
+		c.queue(wsServerMessage{Type: "unsubscribed", Channel: msg.Channel})
+	case eventTyping, eventPresence:
+		c.signal(msg.Type, msg.Channel)
+	case "auth":
+		userID, expiresAt, err := c.cfg.keyring.ValidateJWTExpiry(msg.Token)
+		if err != nil || userID != c.userID {
+			c.queueError("invalid JWT")
+			return
+		}
+		c.expiry.Reset(time.Until(expiresAt))
+		c.queue(wsServerMessage{Type: "authenticated"})
+	case "ping":
+		c.queue(wsServerMessage{Type: "pong"})
+	default:
+		c.queueError("unknown message type %q", msg.Type)
+	}
+}
+
+func (c *wsConn) subscribe(name string, lastEventID int64) {
+	if _, ok := c.channels[name]; ok {
+		c.queue(wsServerMessage{Type: "subscribed", Channel: name})
+		return
+	}
+	if len(c.channels) >= wsMaxChannels {
+		c.queueError("too many channels, the limit is %d", wsMaxChannels)
+		return
+	}
+
+	channel := &wsChannel{}
+	switch {
+	case name == "timeline":
+		channel.subs = append(channel.subs, c.cfg.hub.Subscribe(isChirpEvent, lastEventID))
+	case name == "notifications":
+		channel.subs = append(channel.subs, c.cfg.hub.Subscribe(func(msg pubsub.Message) bool {
+			return msg.Recipient == c.userID
+		}, lastEventID))
+	case strings.HasPrefix(name, "chirp:"):
+		chirpID, err := uuid.Parse(strings.TrimPrefix(name, "chirp:"))
+		if err != nil {
+			c.queueError("invalid chirp ID")
+			return
+		}
+		channel.subs = append(channel.subs,
+			c.cfg.hub.Subscribe(func(msg pubsub.Message) bool {
+				return isChirpEvent(msg) && msg.ChirpID == chirpID
+			}, lastEventID),
+			c.cfg.signalHub.Subscribe(func(msg pubsub.Message) bool {
+				return msg.ChirpID == chirpID && msg.AuthorID != c.userID
+			}, 0),
+		)
+	default:
+		c.queueError("unknown channel %q", name)
+		return
+	}
+
+	c.channels[name] = channel
+	c.queue(wsServerMessage{Type: "subscribed", Channel: name})
+	for _, sub := range channel.subs {
+		go c.forward(name, channel, sub)
+	}
+}
+
+func (c *wsConn) forward(name string, channel *wsChannel, sub *pubsub.Subscription) {
+	for msg := range sub.C() {
+		c.queue(wsServerMessage{
+			Type:    "event",
+			Channel: name,
+			Event:   msg.Type,
+			ID:      msg.ID,
+			Data:    msg.Data,
+		})
+	}
+	if !channel.closed.Load() {
+		// The hub dropped us for falling behind. The client reconnects
+		// and resubscribes with the last event ID it saw.
+		c.close(websocket.CloseTryAgainLater, "too slow")
+	}
+}
+
+// signal relays a typing or presence ping to everyone else in a chirp
+// thread the client is subscribed to
+func (c *wsConn) signal(eventType, name string) {
+	if _, ok := c.channels[name]; !ok || !strings.HasPrefix(name, "chirp:") {
+		c.queueError("subscribe to a chirp channel before sending %s", eventType)
+		return
+	}
+	chirpID := uuid.MustParse(strings.TrimPrefix(name, "chirp:"))
+	c.cfg.publish(c.ctx, c.cfg.signals, pubsub.Message{
+		Type:     eventType,
+		AuthorID: c.userID,
+		ChirpID:  chirpID,
+	}, map[string]uuid.UUID{"user_id": c.userID})
+}
+
+func (ch *wsChannel) close() {
+	ch.closed.Store(true)
+	for _, sub := range ch.subs {
+		sub.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// newTestKeyring returns an HS256 keyring for tests
+func newTestKeyring(t *testing.T, secret string) *auth.Keyring {
+	t.Helper()
+	keyring, err := auth.NewHMACKeyring(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+// newWebSocketServer serves handlerWebSocket with in-memory hubs and no
+// database
+func newWebSocketServer(t *testing.T) (*apiConfig, *httptest.Server) {
+	t.Helper()
+	cfg := &apiConfig{
+		keyring:   newTestKeyring(t, "websocket-test-secret"),
+		hub:       pubsub.NewHub(10),
+		signalHub: pubsub.NewHub(0),
+	}
+	server := httptest.NewServer(http.HandlerFunc(cfg.handlerWebSocket))
+	t.Cleanup(server.Close)
+	return cfg, server
+}
+
+func dialWebSocket(server *httptest.Server, token string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return websocket.DefaultDialer.Dial(url, header)
+}
+
+// readWebSocketMessage reads the next message from the server
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) wsServerMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg := wsServerMessage{}
+	err := conn.ReadJSON(&msg)
+	if err != nil {
+		t.Fatalf("ReadJSON() error = %v", err)
+	}
+	return msg
+}
+
+func TestWebSocketAuthentication(t *testing.T) {
+	cfg, server := newWebSocketServer(t)
+	otherKeyring := newTestKeyring(t, "another-secret")
+	forged, err := otherKeyring.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := cfg.keyring.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "No token", token: "", wantStatus: http.StatusUnauthorized},
+		{name: "Forged token", token: forged, wantStatus: http.StatusUnauthorized},
+		{name: "Valid token", token: valid, wantStatus: http.StatusSwitchingProtocols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := dialWebSocket(server, tt.token)
+			if resp == nil {
+				t.Fatalf("Dial() error = %v, want a response", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Dial() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+		})
+	}
+}
+
+func TestWebSocketSubscribe(t *testing.T) {
+	cfg, server := newWebSocketServer(t)
+	token, err := cfg.keyring.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := dialWebSocket(server, token)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteJSON(wsClientMessage{Type: "subscribe", Channel: "timeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := readWebSocketMessage(t, conn)
+	if msg.Type != "subscribed" || msg.Channel != "timeline" {
+		t.Fatalf("got %+v, want the timeline subscription confirmed", msg)
+	}
+
+	data := json.RawMessage(`{"body":"Hello, WebSocket"}`)
+	err = cfg.hub.Publish(context.Background(), pubsub.Message{Type: eventChirpCreated, Data: data, AuthorID: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Notifications for a single user stay off the timeline
+	err = cfg.hub.Publish(context.Background(), pubsub.Message{Type: eventChirpImportCompleted, Recipient: uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg = readWebSocketMessage(t, conn)
+	if msg.Type != "event" || msg.Channel != "timeline" || msg.Event != eventChirpCreated || msg.ID == 0 {
+		t.Errorf("got %+v, want a chirp.created event on the timeline", msg)
+	}
+	if string(msg.Data) != string(data) {
+		t.Errorf("event data = %s, want %s", msg.Data, data)
+	}
+
+	err = conn.WriteJSON(wsClientMessage{Type: "ping"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = readWebSocketMessage(t, conn)
+	if msg.Type != "pong" {
+		t.Errorf("got %+v, want the pong and nothing from the notification", msg)
+	}
+}
+
+func TestWebSocketClosesWhenTokenExpires(t *testing.T) {
+	cfg, server := newWebSocketServer(t)
+	token, err := cfg.keyring.MakeJWT(uuid.New(), 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := dialWebSocket(server, token)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	closeErr := &websocket.CloseError{}
+	if !errors.As(err, &closeErr) || closeErr.Code != wsCloseTokenExpired {
+		t.Errorf("ReadMessage() error = %v, want close code %d", err, wsCloseTokenExpired)
+	}
+}
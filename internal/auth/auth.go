@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -10,9 +11,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type TokenType string
@@ -20,69 +19,55 @@ type TokenType string
 const (
 	// TokenTypeAccess -
 	TokenTypeAccess TokenType = "chirpy-access"
+	// TokenTypeMFA is issued by login when a second factor is still required
+	TokenTypeMFA TokenType = "chirpy-mfa"
+	// TokenTypeDownload signs links to a file, such as a data export, so
+	// they work without an access token
+	TokenTypeDownload TokenType = "chirpy-download"
 )
 
 // ErrNoAuthHeaderIncluded -
 var ErrNoAuthHeaderIncluded = errors.New("no auth header included in request")
 
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPassword), nil
-}
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
 // MakeJWT -
 func MakeJWT(
 	userID uuid.UUID,
 	tokenSecret string,
 	expiresIn time.Duration,
 ) (string, error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
-	return token.SignedString(signingKey)
-}
-
-// ValidateJWT -
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
-	)
+	keyring, err := NewHMACKeyring(tokenSecret)
 	if err != nil {
-		return uuid.Nil, err
+		return "", err
 	}
+	return keyring.MakeJWT(userID, expiresIn)
+}
 
-	userIDString, err := token.Claims.GetSubject()
+// MakeMFAToken issues a short-lived token that only proves the password
+// step of login succeeded. It is not accepted by ValidateJWT.
+func MakeMFAToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	keyring, err := NewHMACKeyring(tokenSecret)
 	if err != nil {
-		return uuid.Nil, err
+		return "", err
 	}
+	return keyring.MakeMFAToken(userID, expiresIn)
+}
 
-	issuer, err := token.Claims.GetIssuer()
+// ValidateJWT -
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	keyring, err := NewHMACKeyring(tokenSecret)
 	if err != nil {
 		return uuid.Nil, err
 	}
-	if issuer != string(TokenTypeAccess) {
-		return uuid.Nil, errors.New("invalid issuer")
-	}
+	return keyring.ValidateJWT(tokenString)
+}
 
-	id, err := uuid.Parse(userIDString)
+// ValidateMFAToken validates a token issued by MakeMFAToken
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	keyring, err := NewHMACKeyring(tokenSecret)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return uuid.Nil, err
 	}
-	return id, nil
+	return keyring.ValidateMFAToken(tokenString)
 }
 
 // GetBearerToken -
@@ -101,6 +86,11 @@ func GetBearerToken(headers http.Header) (string, error) {
 
 // MakeRefreshToken generates a random 256-bit (32-byte) hex-encoded string
 func MakeRefreshToken() (string, error) {
+	return MakeSecureToken()
+}
+
+// MakeSecureToken generates a random 256-bit (32-byte) hex-encoded string
+func MakeSecureToken() (string, error) {
 	randomBytes := make([]byte, 32)
 	_, err := rand.Read(randomBytes)
 	if err != nil {
@@ -109,6 +99,13 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(randomBytes), nil
 }
 
+// HashToken returns the hex-encoded SHA-256 of a token so it can be stored
+// and looked up without keeping the token itself
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 
 func GetAPIKey(headers http.Header, polkaKey string) (string, error) {
 	authHeader := headers.Get("Authorization")
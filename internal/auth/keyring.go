@@ -0,0 +1,377 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// SharedSecretKeyID identifies the HS256 key built from JWT_SECRET. Tokens
+// issued before key IDs were introduced carry no kid and are checked
+// against it.
+const SharedSecretKeyID = "shared-secret"
+
+// signingKey is one entry of a Keyring. Keys loaded from a public key only
+// can verify tokens but never sign them.
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+	retired   bool
+}
+
+// Keyring holds every key that tokens may be signed with, identified by
+// kid. New tokens are signed with the active key and any key that has not
+// been retired is accepted, which allows keys to be rotated without
+// invalidating tokens that are still in flight.
+type Keyring struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]*signingKey
+}
+
+// NewKeyring returns an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]*signingKey{}}
+}
+
+// NewHMACKeyring returns a keyring with a single active HS256 key. The
+// secret can't be empty.
+func NewHMACKeyring(secret string) (*Keyring, error) {
+	k := NewKeyring()
+	err := k.AddHMAC(SharedSecretKeyID, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	k.active = SharedSecretKeyID
+	return k, nil
+}
+
+// AddHMAC adds a shared secret HS256 key. HMAC keys are never published in
+// the JWKS.
+func (k *Keyring) AddHMAC(kid string, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("empty HMAC secret")
+	}
+	return k.add(&signingKey{
+		id:        kid,
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	})
+}
+
+// AddPrivateKey adds an RSA (RS256) or Ed25519 (EdDSA) key that can sign
+// and verify tokens
+func (k *Keyring) AddPrivateKey(kid string, key crypto.PrivateKey) error {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return k.add(&signingKey{id: kid, method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey})
+	case ed25519.PrivateKey:
+		return k.add(&signingKey{id: kid, method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.Public()})
+	default:
+		return fmt.Errorf("key %s: unsupported private key type %T", kid, key)
+	}
+}
+
+// AddPublicKey adds an RSA or Ed25519 key that can only verify tokens
+func (k *Keyring) AddPublicKey(kid string, key crypto.PublicKey) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return k.add(&signingKey{id: kid, method: jwt.SigningMethodRS256, verifyKey: key})
+	case ed25519.PublicKey:
+		return k.add(&signingKey{id: kid, method: jwt.SigningMethodEdDSA, verifyKey: key})
+	default:
+		return fmt.Errorf("key %s: unsupported public key type %T", kid, key)
+	}
+}
+
+func (k *Keyring) add(key *signingKey) error {
+	if key.id == "" {
+		return errors.New("key ID is required")
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[key.id]; ok {
+		return fmt.Errorf("duplicate key ID %s", key.id)
+	}
+	k.keys[key.id] = key
+	return nil
+}
+
+// SetActive selects the key new tokens are signed with
+func (k *Keyring) SetActive(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[kid]
+	if !ok {
+		return fmt.Errorf("unknown key ID %s", kid)
+	}
+	if key.signKey == nil {
+		return fmt.Errorf("key %s has no private key", kid)
+	}
+	if key.retired {
+		return fmt.Errorf("key %s is retired", kid)
+	}
+	k.active = kid
+	return nil
+}
+
+// Retire stops a key from being accepted or published
+func (k *Keyring) Retire(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[kid]
+	if !ok {
+		return fmt.Errorf("unknown key ID %s", kid)
+	}
+	if kid == k.active {
+		return fmt.Errorf("key %s is active and can't be retired", kid)
+	}
+	key.retired = true
+	return nil
+}
+
+// LoadKeyring reads every <kid>.pem file in dir. Files may hold a PKCS#8 or
+// PKCS#1 private key, or a PKIX public key for keys that should still be
+// accepted after their private half has been destroyed.
+func LoadKeyring(dir string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	k := NewKeyring()
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", path)
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			err = k.AddPrivateKey(kid, key)
+			if err != nil {
+				return nil, err
+			}
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			err = k.AddPrivateKey(kid, key)
+			if err != nil {
+				return nil, err
+			}
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			err = k.AddPublicKey(kid, key)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+		}
+	}
+	return k, nil
+}
+
+// MakeJWT signs an access token with the active key
+func (k *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.makeJWT(userID, expiresIn, TokenTypeAccess)
+}
+
+// MakeMFAToken signs an MFA challenge token with the active key
+func (k *Keyring) MakeMFAToken(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.makeJWT(userID, expiresIn, TokenTypeMFA)
+}
+
+// MakeDownloadToken signs a token that grants downloading the file with
+// fileID
+func (k *Keyring) MakeDownloadToken(fileID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.makeJWT(fileID, expiresIn, TokenTypeDownload)
+}
+
+func (k *Keyring) makeJWT(userID uuid.UUID, expiresIn time.Duration, tokenType TokenType) (string, error) {
+	k.mu.RLock()
+	key, ok := k.keys[k.active]
+	k.mu.RUnlock()
+	if !ok {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(key.method, jwt.RegisteredClaims{
+		Issuer:    string(tokenType),
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+	})
+	token.Header["kid"] = key.id
+	return token.SignedString(key.signKey)
+}
+
+// ValidateJWT validates an access token signed by any key that has not
+// been retired
+func (k *Keyring) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	userID, _, err := k.validateJWT(tokenString, TokenTypeAccess)
+	return userID, err
+}
+
+// ValidateJWTExpiry is ValidateJWT that also returns when the token
+// expires, for connections that outlive a single request
+func (k *Keyring) ValidateJWTExpiry(tokenString string) (uuid.UUID, time.Time, error) {
+	return k.validateJWT(tokenString, TokenTypeAccess)
+}
+
+// ValidateMFAToken validates an MFA challenge token
+func (k *Keyring) ValidateMFAToken(tokenString string) (uuid.UUID, error) {
+	userID, _, err := k.validateJWT(tokenString, TokenTypeMFA)
+	return userID, err
+}
+
+// ValidateDownloadToken validates a download token and returns the ID of
+// the file it grants
+func (k *Keyring) ValidateDownloadToken(tokenString string) (uuid.UUID, error) {
+	fileID, _, err := k.validateJWT(tokenString, TokenTypeDownload)
+	return fileID, err
+}
+
+func (k *Keyring) validateJWT(tokenString string, tokenType TokenType) (uuid.UUID, time.Time, error) {
+	claimsStruct := jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&claimsStruct,
+		k.keyFunc,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}),
+	)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+
+	userIDString, err := token.Claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+
+	issuer, err := token.Claims.GetIssuer()
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	if issuer != string(tokenType) {
+		return uuid.Nil, time.Time{}, errors.New("invalid issuer")
+	}
+
+	id, err := uuid.Parse(userIDString)
+	if err != nil {
+		return uuid.Nil, time.Time{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return uuid.Nil, time.Time{}, errors.New("token has no expiry")
+	}
+	return id, expiresAt.Time, nil
+}
+
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = SharedSecretKeyID
+	}
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok || key.retired {
+		return nil, fmt.Errorf("unknown or retired key ID %q", kid)
+	}
+	// Never let the token pick the algorithm, otherwise a public key could
+	// be used as an HMAC secret
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every asymmetric key that has not been
+// retired
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		if key.retired {
+			continue
+		}
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+	return jwks
+}
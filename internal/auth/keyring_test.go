@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	keyring := NewKeyring()
+	if err := keyring.AddPrivateKey("old", edKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.AddPrivateKey("new", rsaKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.AddHMAC(SharedSecretKeyID, []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	userID := uuid.New()
+	keyring.SetActive("old")
+	oldToken, _ := keyring.MakeJWT(userID, time.Hour)
+	keyring.SetActive("new")
+	newToken, _ := keyring.MakeJWT(userID, time.Hour)
+	legacyToken, _ := MakeJWT(userID, "secret", time.Hour)
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken, "legacy": legacyToken} {
+		gotUserID, err := keyring.ValidateJWT(token)
+		if err != nil {
+			t.Errorf("ValidateJWT() %s token error = %v", name, err)
+		}
+		if gotUserID != userID {
+			t.Errorf("ValidateJWT() %s token gotUserID = %v, want %v", name, gotUserID, userID)
+		}
+	}
+
+	if err := keyring.Retire("new"); err == nil {
+		t.Errorf("Retire() should refuse to retire the active key")
+	}
+	if err := keyring.Retire("old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keyring.ValidateJWT(oldToken); err == nil {
+		t.Errorf("ValidateJWT() should reject tokens signed by a retired key")
+	}
+	if _, err := keyring.ValidateJWT(newToken); err != nil {
+		t.Errorf("ValidateJWT() error = %v", err)
+	}
+}
+
+func TestKeyringRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keyring := NewKeyring()
+	keyring.AddPrivateKey("rsa", rsaKey)
+	keyring.SetActive("rsa")
+
+	// Sign an HS256 token using the public key as the HMAC secret
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   uuid.New().String(),
+	})
+	token.Header["kid"] = "rsa"
+	forged, _ := token.SignedString(pubDER)
+
+	if _, err := keyring.ValidateJWT(forged); err == nil {
+		t.Errorf("ValidateJWT() should reject a token whose algorithm doesn't match its key")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	writePEM(t, dir, "ed.pem", "PRIVATE KEY", edDER)
+	writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	pubDER, _ := x509.MarshalPKIXPublicKey(edPub)
+	writePEM(t, dir, "verify-only.pem", "PUBLIC KEY", pubDER)
+
+	keyring, err := LoadKeyring(dir)
+	if err != nil {
+		t.Fatalf("LoadKeyring() error = %v", err)
+	}
+	if err := keyring.SetActive("verify-only"); err == nil {
+		t.Errorf("SetActive() should refuse a key without a private half")
+	}
+	if err := keyring.SetActive("ed"); err != nil {
+		t.Fatal(err)
+	}
+	keyring.AddHMAC(SharedSecretKeyID, []byte("secret"))
+
+	jwks := keyring.JWKS()
+	gotKIDs := []string{}
+	for _, key := range jwks.Keys {
+		gotKIDs = append(gotKIDs, key.KeyID+"/"+key.Algorithm)
+	}
+	wantKIDs := []string{"ed/EdDSA", "rsa/RS256", "verify-only/EdDSA"}
+	if len(gotKIDs) != len(wantKIDs) {
+		t.Fatalf("JWKS() keys = %v, want %v", gotKIDs, wantKIDs)
+	}
+	for i := range wantKIDs {
+		if gotKIDs[i] != wantKIDs[i] {
+			t.Errorf("JWKS() keys = %v, want %v", gotKIDs, wantKIDs)
+		}
+	}
+}
+
+func TestNewHMACKeyringRejectsEmptySecret(t *testing.T) {
+	_, err := NewHMACKeyring("")
+	if err == nil {
+		t.Error("NewHMACKeyring() error = nil, want an error for an empty secret")
+	}
+}
+
+func TestKeyringValidateJWTExpiry(t *testing.T) {
+	keyring, err := NewHMACKeyring("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	token, err := keyring.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotUserID, expiresAt, err := keyring.ValidateJWTExpiry(token)
+	if err != nil || gotUserID != userID {
+		t.Fatalf("ValidateJWTExpiry() = %v, %v, want %v, nil", gotUserID, err, userID)
+	}
+	if until := time.Until(expiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("ValidateJWTExpiry() expiresAt is %v from now, want about an hour", until)
+	}
+}
+
+func TestKeyringDownloadToken(t *testing.T) {
+	keyring, err := NewHMACKeyring("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileID := uuid.New()
+
+	token, err := keyring.MakeDownloadToken(fileID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotFileID, err := keyring.ValidateDownloadToken(token); err != nil || gotFileID != fileID {
+		t.Errorf("ValidateDownloadToken() = %v, %v, want %v, nil", gotFileID, err, fileID)
+	}
+	if _, err := keyring.ValidateJWT(token); err == nil {
+		t.Errorf("ValidateJWT() should reject download tokens")
+	}
+
+	accessToken, _ := keyring.MakeJWT(fileID, time.Minute)
+	if _, err := keyring.ValidateDownloadToken(accessToken); err == nil {
+		t.Errorf("ValidateDownloadToken() should reject access tokens")
+	}
+	expired, _ := keyring.MakeDownloadToken(fileID, -time.Minute)
+	if _, err := keyring.ValidateDownloadToken(expired); err == nil {
+		t.Errorf("ValidateDownloadToken() should reject expired tokens")
+	}
+}
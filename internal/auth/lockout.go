@@ -0,0 +1,66 @@
+package auth
+
+import "time"
+
+// LockoutPolicy decides how long a client has to wait after failed login
+// attempts. The first FreeAttempts failures cost nothing, after that the
+// wait doubles with every failure starting at BaseDelay and capped at
+// MaxDelay. Reaching LockoutThreshold failures locks the key for
+// LockoutDuration. Failures older than Window are forgotten.
+type LockoutPolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+// AccountLockoutPolicy applies to failures against a single email address
+var AccountLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+	Window:           time.Hour,
+}
+
+// IPLockoutPolicy applies to failures from a single client IP. It is looser
+// than AccountLockoutPolicy since many users can share an address.
+var IPLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     20,
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	LockoutThreshold: 100,
+	LockoutDuration:  time.Hour,
+	Window:           time.Hour,
+}
+
+// RetryAfter returns how long to wait from now before another attempt is
+// allowed, given the number of recent failures and when the last one
+// happened. Zero means an attempt is allowed now.
+func (p LockoutPolicy) RetryAfter(failures int, lastFailure, lockedUntil, now time.Time) time.Duration {
+	if now.Before(lockedUntil) {
+		return lockedUntil.Sub(now)
+	}
+	if now.Sub(lastFailure) > p.Window || failures <= p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if wait := lastFailure.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// ShouldLock reports whether failures has just reached the lockout threshold
+func (p LockoutPolicy) ShouldLock(failures int) bool {
+	return failures >= p.LockoutThreshold && (failures-p.LockoutThreshold)%p.LockoutThreshold == 0
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyRetryAfter(t *testing.T) {
+	policy := LockoutPolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         8 * time.Second,
+		LockoutThreshold: 10,
+		LockoutDuration:  time.Minute,
+		Window:           time.Hour,
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		failures    int
+		lastFailure time.Time
+		lockedUntil time.Time
+		want        time.Duration
+	}{
+		{name: "No failures", failures: 0, lastFailure: time.Time{}, want: 0},
+		{name: "Within free attempts", failures: 3, lastFailure: now, want: 0},
+		{name: "First delayed attempt", failures: 4, lastFailure: now, want: time.Second},
+		{name: "Delay doubles", failures: 6, lastFailure: now, want: 4 * time.Second},
+		{name: "Delay is capped", failures: 9, lastFailure: now, want: 8 * time.Second},
+		{name: "Delay already waited out", failures: 4, lastFailure: now.Add(-2 * time.Second), want: 0},
+		{name: "Failures outside the window", failures: 9, lastFailure: now.Add(-2 * time.Hour), want: 0},
+		{name: "Locked", failures: 10, lastFailure: now, lockedUntil: now.Add(time.Minute), want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.RetryAfter(tt.failures, tt.lastFailure, tt.lockedUntil, now)
+			if got != tt.want {
+				t.Errorf("RetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLockoutPolicyShouldLock(t *testing.T) {
+	policy := LockoutPolicy{LockoutThreshold: 10}
+	for failures, want := range map[int]bool{1: false, 9: false, 10: true, 11: false, 20: true} {
+		if got := policy.ShouldLock(failures); got != want {
+			t.Errorf("ShouldLock(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestPasswordHasher(t *testing.T) {
+	argonHasher := &PasswordHasher{Algorithm: AlgorithmArgon2id, Argon2: testArgon2Params}
+	bcryptHasher := &PasswordHasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+	longPassword := strings.Repeat("a", 80)
+
+	argonHash, err := argonHasher.Hash(longPassword)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Hash() = %v, want an argon2id PHC string", argonHash)
+	}
+	bcryptHash, err := bcryptHasher.Hash("correctPassword123!")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+	}{
+		{name: "Argon2id correct password", password: longPassword, hash: argonHash, want: true},
+		// bcrypt would have ignored everything after the 72nd byte
+		{name: "Argon2id uses the whole password", password: longPassword[:79] + "b", hash: argonHash, want: false},
+		{name: "Bcrypt correct password", password: "correctPassword123!", hash: bcryptHash, want: true},
+		{name: "Bcrypt wrong password", password: "wrongPassword", hash: bcryptHash, want: false},
+		{name: "Corrupt argon2id hash", password: longPassword, hash: "$argon2id$v=19$m=1024$salt$key", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPasswordHash(tt.password, tt.hash); got != tt.want {
+				t.Errorf("CheckPasswordHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argonHasher := &PasswordHasher{Algorithm: AlgorithmArgon2id, Argon2: testArgon2Params}
+	strongerParams := testArgon2Params
+	strongerParams.Iterations = 2
+	strongerHasher := &PasswordHasher{Algorithm: AlgorithmArgon2id, Argon2: strongerParams}
+	bcryptHasher := &PasswordHasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+	costlierBcrypt := &PasswordHasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1}
+
+	argonHash, _ := argonHasher.Hash("password")
+	bcryptHash, _ := bcryptHasher.Hash("password")
+
+	tests := []struct {
+		name   string
+		hasher *PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{name: "Same argon2id parameters", hasher: argonHasher, hash: argonHash, want: false},
+		{name: "Stronger argon2id parameters", hasher: strongerHasher, hash: argonHash, want: true},
+		{name: "Bcrypt hash with argon2id configured", hasher: argonHasher, hash: bcryptHash, want: true},
+		{name: "Same bcrypt cost", hasher: bcryptHasher, hash: bcryptHash, want: false},
+		{name: "Higher bcrypt cost", hasher: costlierBcrypt, hash: bcryptHash, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
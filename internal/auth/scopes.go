@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scope limits what a personal access token can do. Access JWTs carry every
+// scope.
+type Scope string
+
+const (
+	ScopeChirpsRead   Scope = "chirps:read"
+	ScopeChirpsWrite  Scope = "chirps:write"
+	ScopeProfileWrite Scope = "profile:write"
+)
+
+// AllScopes lists every scope a token can be granted
+var AllScopes = []Scope{
+	ScopeChirpsRead,
+	ScopeChirpsWrite,
+	ScopeProfileWrite,
+}
+
+// personalAccessTokenPrefix makes tokens easy to tell apart from JWTs and
+// easy to spot in leaked secrets scans
+const personalAccessTokenPrefix = "chirpy_pat_"
+
+// ParseScopes validates scope names and removes duplicates
+func ParseScopes(names []string) ([]Scope, error) {
+	scopes := []Scope{}
+	for _, name := range names {
+		scope := Scope(strings.TrimSpace(name))
+		if !slices.Contains(AllScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", name)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// HasScope reports whether granted includes want
+func HasScope(granted []string, want Scope) bool {
+	return slices.Contains(granted, string(want))
+}
+
+// MakePersonalAccessToken generates a new random personal access token
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeSecureToken()
+	if err != nil {
+		return "", err
+	}
+	return personalAccessTokenPrefix + token, nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token looks like one made
+// by MakePersonalAccessToken rather than a JWT
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
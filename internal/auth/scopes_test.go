@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		names      []string
+		wantScopes []Scope
+		wantErr    bool
+	}{
+		{
+			name:       "Valid scopes",
+			names:      []string{"chirps:read", "chirps:write"},
+			wantScopes: []Scope{ScopeChirpsRead, ScopeChirpsWrite},
+			wantErr:    false,
+		},
+		{
+			name:       "Duplicate scopes",
+			names:      []string{"profile:write", "profile:write"},
+			wantScopes: []Scope{ScopeProfileWrite},
+			wantErr:    false,
+		},
+		{
+			name:       "Unknown scope",
+			names:      []string{"chirps:read", "admin"},
+			wantScopes: nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotScopes, err := ParseScopes(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseScopes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotScopes, tt.wantScopes) {
+				t.Errorf("ParseScopes() gotScopes = %v, want %v", gotScopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestIsPersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("IsPersonalAccessToken(%q) = false, want true", token)
+	}
+	if IsPersonalAccessToken("eyJhbGciOiJIUzI1NiJ9.e30.sig") {
+		t.Errorf("IsPersonalAccessToken() should be false for JWTs")
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B test vectors for SHA1, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		name     string
+		unixTime int64
+		wantCode string
+	}{
+		{name: "T=59", unixTime: 59, wantCode: "287082"},
+		{name: "T=1111111109", unixTime: 1111111109, wantCode: "081804"},
+		{name: "T=1234567890", unixTime: 1234567890, wantCode: "005924"},
+		{name: "T=20000000000", unixTime: 20000000000, wantCode: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCode, err := TOTPCode(secret, time.Unix(tt.unixTime, 0))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if gotCode != tt.wantCode {
+				t.Errorf("TOTPCode() gotCode = %v, want %v", gotCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, _ := TOTPCode(secret, now)
+	previousCode, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	staleCode, _ := TOTPCode(secret, now.Add(-5*time.Minute))
+
+	tests := []struct {
+		name   string
+		code   string
+		wantOK bool
+	}{
+		{name: "Current code", code: code, wantOK: true},
+		{name: "Previous period", code: previousCode, wantOK: true},
+		{name: "Stale code", code: staleCode, wantOK: false},
+		{name: "Wrong length", code: "12345", wantOK: false},
+		{name: "Empty code", code: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, gotOK := ValidateTOTP(secret, tt.code, now)
+			if gotOK != tt.wantOK {
+				t.Errorf("ValidateTOTP() gotOK = %v, want %v", gotOK, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	key := make([]byte, 32)
+	otherKey := make([]byte, 32)
+	otherKey[0] = 1
+
+	ciphertext, err := EncryptSecret("JBSWY3DPEHPK3PXP", key)
+	if err != nil {
+		t.Fatalf("EncryptSecret() error = %v", err)
+	}
+	plaintext, err := DecryptSecret(ciphertext, key)
+	if err != nil {
+		t.Fatalf("DecryptSecret() error = %v", err)
+	}
+	if plaintext != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("DecryptSecret() = %v, want %v", plaintext, "JBSWY3DPEHPK3PXP")
+	}
+	if _, err := DecryptSecret(ciphertext, otherKey); err == nil {
+		t.Errorf("DecryptSecret() with wrong key should fail")
+	}
+}
+
+func TestValidateMFAToken(t *testing.T) {
+	userID := uuid.New()
+	mfaToken, _ := MakeMFAToken(userID, "secret", time.Minute)
+	accessToken, _ := MakeJWT(userID, "secret", time.Minute)
+
+	if _, err := ValidateMFAToken(mfaToken, "secret"); err != nil {
+		t.Errorf("ValidateMFAToken() error = %v", err)
+	}
+	if _, err := ValidateJWT(mfaToken, "secret"); err == nil {
+		t.Errorf("ValidateJWT() should reject MFA tokens")
+	}
+	if _, err := ValidateMFAToken(accessToken, "secret"); err == nil {
+		t.Errorf("ValidateMFAToken() should reject access tokens")
+	}
+}
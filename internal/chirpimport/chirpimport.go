@@ -0,0 +1,180 @@
+// Package chirpimport reads archives of chirps, such as history exported
+// from another platform, so they can be imported into Chirpy.
+//
+// An archive is JSON Lines, one object with a body and created_at per line,
+// or CSV with a header row naming the body and created_at columns. Other
+// fields and columns are ignored. created_at is an RFC 3339 timestamp.
+package chirpimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Archive formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// MaxRows is the most chirps one archive can hold
+const MaxRows = 10000
+
+// maxLineBytes is the longest JSON Lines line that's read
+const maxLineBytes = 64 << 10
+
+// Row is a chirp read from an archive
+type Row struct {
+	// Line is where the chirp is in the archive, counting from 1
+	Line      int
+	Body      string
+	CreatedAt time.Time
+}
+
+// RowError explains why the chirp on Line wasn't imported
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// FormatForContentType returns the archive format sent with contentType
+func FormatForContentType(contentType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return FormatJSONL, true
+	case "text/csv":
+		return FormatCSV, true
+	}
+	return "", false
+}
+
+// Read returns the chirps in an archive. Rows that can't be read are
+// returned as RowErrors, an error means the archive as a whole is unusable.
+func Read(r io.Reader, format string) ([]Row, []RowError, error) {
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r)
+	case FormatCSV:
+		return readCSV(r)
+	}
+	return nil, nil, fmt.Errorf("unknown archive format %q", format)
+}
+
+func readJSONL(r io.Reader) ([]Row, []RowError, error) {
+	rows := []Row{}
+	rowErrors := []RowError{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows)+len(rowErrors) >= MaxRows {
+			return nil, nil, fmt.Errorf("archive has more than %d chirps", MaxRows)
+		}
+		record := struct {
+			Body      *string `json:"body"`
+			CreatedAt *string `json:"created_at"`
+		}{}
+		err := json.Unmarshal([]byte(text), &record)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Error: "invalid JSON"})
+			continue
+		}
+		row, rowErr := newRow(line, record.Body, record.CreatedAt)
+		if rowErr != nil {
+			rowErrors = append(rowErrors, *rowErr)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, nil, fmt.Errorf("line %d is longer than %d bytes", line+1, maxLineBytes)
+	}
+	if scanner.Err() != nil {
+		return nil, nil, scanner.Err()
+	}
+	return rows, rowErrors, nil
+}
+
+func readCSV(r io.Reader) ([]Row, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("archive is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	bodyColumn, createdAtColumn := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "body":
+			bodyColumn = i
+		case "created_at":
+			createdAtColumn = i
+		}
+	}
+	if bodyColumn < 0 || createdAtColumn < 0 {
+		return nil, nil, errors.New("the header row must have body and created_at columns")
+	}
+
+	rows := []Row{}
+	rowErrors := []RowError{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows)+len(rowErrors) >= MaxRows {
+			return nil, nil, fmt.Errorf("archive has more than %d chirps", MaxRows)
+		}
+		line, _ := reader.FieldPos(0)
+		var body, createdAt *string
+		if bodyColumn < len(record) {
+			body = &record[bodyColumn]
+		}
+		if createdAtColumn < len(record) {
+			createdAt = &record[createdAtColumn]
+		}
+		row, rowErr := newRow(line, body, createdAt)
+		if rowErr != nil {
+			rowErrors = append(rowErrors, *rowErr)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+func newRow(line int, body, createdAt *string) (Row, *RowError) {
+	if body == nil || strings.TrimSpace(*body) == "" {
+		return Row{}, &RowError{Line: line, Error: "body is required"}
+	}
+	if createdAt == nil || strings.TrimSpace(*createdAt) == "" {
+		return Row{}, &RowError{Line: line, Error: "created_at is required"}
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(*createdAt))
+	if err != nil {
+		return Row{}, &RowError{Line: line, Error: "created_at must be an RFC 3339 timestamp"}
+	}
+	return Row{Line: line, Body: *body, CreatedAt: parsed.UTC()}, nil
+}
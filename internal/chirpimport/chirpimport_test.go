@@ -0,0 +1,134 @@
+package chirpimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	first := time.Date(2019, 3, 1, 9, 30, 0, 0, time.UTC)
+	second := time.Date(2020, 7, 4, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		format     string
+		archive    string
+		wantRows   []Row
+		wantErrors []RowError
+		wantErr    bool
+	}{
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			archive: `{"body": "Hello", "created_at": "2019-03-01T09:30:00Z", "id": 7}
+
+{"body": "Again", "created_at": "2020-07-04T20:00:00+02:00"}
+`,
+			wantRows: []Row{
+				{Line: 1, Body: "Hello", CreatedAt: first},
+				{Line: 3, Body: "Again", CreatedAt: second},
+			},
+			wantErrors: []RowError{},
+		},
+		{
+			name:   "jsonl row errors",
+			format: FormatJSONL,
+			archive: `{"body": "Hello", "created_at": "2019-03-01T09:30:00Z"}
+not json
+{"created_at": "2019-03-01T09:30:00Z"}
+{"body": "No date"}
+{"body": "Bad date", "created_at": "yesterday"}
+`,
+			wantRows: []Row{
+				{Line: 1, Body: "Hello", CreatedAt: first},
+			},
+			wantErrors: []RowError{
+				{Line: 2, Error: "invalid JSON"},
+				{Line: 3, Error: "body is required"},
+				{Line: 4, Error: "created_at is required"},
+				{Line: 5, Error: "created_at must be an RFC 3339 timestamp"},
+			},
+		},
+		{
+			name:   "csv",
+			format: FormatCSV,
+			archive: "id,created_at,body\n" +
+				"1,2019-03-01T09:30:00Z,Hello\n" +
+				"2,2020-07-04T18:00:00Z,\"Multi\nline, with comma\"\n" +
+				"3,2020-07-04T18:00:00Z\n",
+			wantRows: []Row{
+				{Line: 2, Body: "Hello", CreatedAt: first},
+				{Line: 3, Body: "Multi\nline, with comma", CreatedAt: second},
+			},
+			wantErrors: []RowError{
+				{Line: 5, Error: "body is required"},
+			},
+		},
+		{
+			name:    "csv without a body column",
+			format:  FormatCSV,
+			archive: "text,created_at\nHello,2019-03-01T09:30:00Z\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty csv",
+			format:  FormatCSV,
+			archive: "",
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			archive: "<chirps/>",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := Read(strings.NewReader(tt.archive), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("Read() rows = %+v, want %+v", rows, tt.wantRows)
+			}
+			if !reflect.DeepEqual(rowErrors, tt.wantErrors) {
+				t.Errorf("Read() row errors = %+v, want %+v", rowErrors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestReadTooManyRows(t *testing.T) {
+	line := `{"body": "Hello", "created_at": "2019-03-01T09:30:00Z"}` + "\n"
+	_, _, err := Read(strings.NewReader(strings.Repeat(line, MaxRows+1)), FormatJSONL)
+	if err == nil {
+		t.Fatal("Read() accepted more than MaxRows chirps")
+	}
+}
+
+func TestFormatForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		wantOK      bool
+	}{
+		{"application/x-ndjson", FormatJSONL, true},
+		{"application/jsonl; charset=utf-8", FormatJSONL, true},
+		{"text/csv; charset=utf-8", FormatCSV, true},
+		{"application/json", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FormatForContentType(tt.contentType)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("FormatForContentType(%q) = %q, %v, want %q, %v", tt.contentType, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: chirp_imports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueChirpImports = `-- name: ClaimDueChirpImports :many
+UPDATE chirp_imports
+SET next_attempt_at = $2::timestamptz, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM chirp_imports
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id
+`
+
+type ClaimDueChirpImportsParams struct {
+	Limit      int32
+	LeaseUntil time.Time
+}
+
+// Pushes next_attempt_at forward so an import that is running isn't picked
+// up again, even by another server, until lease_until
+func (q *Queries) ClaimDueChirpImports(ctx context.Context, arg ClaimDueChirpImportsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueChirpImports, arg.Limit, arg.LeaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeChirpImport = `-- name: CompleteChirpImport :exec
+UPDATE chirp_imports
+SET status = 'completed', archive = NULL, total_rows = $2, imported_rows = $3,
+    duplicate_rows = $4, failed_rows = $5, row_errors = $6, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+type CompleteChirpImportParams struct {
+	ID            uuid.UUID
+	TotalRows     int32
+	ImportedRows  int32
+	DuplicateRows int32
+	FailedRows    int32
+	RowErrors     json.RawMessage
+}
+
+// The archive isn't needed once its chirps are in
+func (q *Queries) CompleteChirpImport(ctx context.Context, arg CompleteChirpImportParams) error {
+	_, err := q.db.ExecContext(ctx, completeChirpImport,
+		arg.ID,
+		arg.TotalRows,
+		arg.ImportedRows,
+		arg.DuplicateRows,
+		arg.FailedRows,
+		arg.RowErrors,
+	)
+	return err
+}
+
+const countChirpImportsSince = `-- name: CountChirpImportsSince :one
+SELECT COUNT(*) FROM chirp_imports
+WHERE user_id = $1 AND created_at > $2
+`
+
+type CountChirpImportsSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountChirpImportsSince(ctx context.Context, arg CountChirpImportsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpImportsSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createChirpImport = `-- name: CreateChirpImport :one
+INSERT INTO chirp_imports (id, user_id, status, format, archive, next_attempt_at, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, 'pending', $2, $3, $4, NOW(), NOW())
+ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+RETURNING id, user_id, status, format, error, total_rows, imported_rows, duplicate_rows, failed_rows, row_errors, created_at, updated_at, completed_at
+`
+
+type CreateChirpImportParams struct {
+	UserID        uuid.UUID
+	Format        string
+	Archive       []byte
+	NextAttemptAt time.Time
+}
+
+type CreateChirpImportRow struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Status        string
+	Format        string
+	Error         sql.NullString
+	TotalRows     int32
+	ImportedRows  int32
+	DuplicateRows int32
+	FailedRows    int32
+	RowErrors     json.RawMessage
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CompletedAt   sql.NullTime
+}
+
+// No rows means the user already has a pending import
+func (q *Queries) CreateChirpImport(ctx context.Context, arg CreateChirpImportParams) (CreateChirpImportRow, error) {
+	row := q.db.QueryRowContext(ctx, createChirpImport,
+		arg.UserID,
+		arg.Format,
+		arg.Archive,
+		arg.NextAttemptAt,
+	)
+	var i CreateChirpImportRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.Format,
+		&i.Error,
+		&i.TotalRows,
+		&i.ImportedRows,
+		&i.DuplicateRows,
+		&i.FailedRows,
+		&i.RowErrors,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const failChirpImport = `-- name: FailChirpImport :exec
+UPDATE chirp_imports
+SET status = 'failed', archive = NULL, error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+type FailChirpImportParams struct {
+	ID    uuid.UUID
+	Error sql.NullString
+}
+
+func (q *Queries) FailChirpImport(ctx context.Context, arg FailChirpImportParams) error {
+	_, err := q.db.ExecContext(ctx, failChirpImport, arg.ID, arg.Error)
+	return err
+}
+
+const getChirpImport = `-- name: GetChirpImport :one
+SELECT id, user_id, status, format, error, total_rows, imported_rows, duplicate_rows, failed_rows, row_errors, created_at, updated_at, completed_at
+FROM chirp_imports
+WHERE id = $1
+`
+
+type GetChirpImportRow struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Status        string
+	Format        string
+	Error         sql.NullString
+	TotalRows     int32
+	ImportedRows  int32
+	DuplicateRows int32
+	FailedRows    int32
+	RowErrors     json.RawMessage
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CompletedAt   sql.NullTime
+}
+
+// Everything but the archive, which can be large
+func (q *Queries) GetChirpImport(ctx context.Context, id uuid.UUID) (GetChirpImportRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpImport, id)
+	var i GetChirpImportRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.Format,
+		&i.Error,
+		&i.TotalRows,
+		&i.ImportedRows,
+		&i.DuplicateRows,
+		&i.FailedRows,
+		&i.RowErrors,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getPendingChirpImportArchive = `-- name: GetPendingChirpImportArchive :one
+SELECT user_id, format, archive FROM chirp_imports
+WHERE id = $1 AND status = 'pending'
+FOR UPDATE
+`
+
+type GetPendingChirpImportArchiveRow struct {
+	UserID  uuid.UUID
+	Format  string
+	Archive []byte
+}
+
+// Locks the import, a server that runs it again after the lease waits and
+// then finds it isn't pending anymore
+func (q *Queries) GetPendingChirpImportArchive(ctx context.Context, id uuid.UUID) (GetPendingChirpImportArchiveRow, error) {
+	row := q.db.QueryRowContext(ctx, getPendingChirpImportArchive, id)
+	var i GetPendingChirpImportArchiveRow
+	err := row.Scan(&i.UserID, &i.Format, &i.Archive)
+	return i, err
+}
+
+const importChirp = `-- name: ImportChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+SELECT gen_random_uuid(), $1::timestamptz, NOW(), $2::text, $3::uuid
+WHERE NOT EXISTS (
+    SELECT 1 FROM chirps
+    WHERE chirps.user_id = $3::uuid
+    AND chirps.created_at = $1::timestamptz
+    AND chirps.body = $2::text
+)
+RETURNING id
+`
+
+type ImportChirpParams struct {
+	CreatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+}
+
+// Adds a chirp with its original created_at, unless the user already has
+// one with the same body and created_at. No rows means it's a duplicate.
+func (q *Queries) ImportChirp(ctx context.Context, arg ImportChirpParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, importChirp, arg.CreatedAt, arg.Body, arg.UserID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const lockUserForImport = `-- name: LockUserForImport :exec
+SELECT id FROM users
+WHERE id = $1
+FOR NO KEY UPDATE
+`
+
+// Imports for the same user run one at a time, so they can't both add a
+// chirp neither has seen yet
+func (q *Queries) LockUserForImport(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockUserForImport, id)
+	return err
+}
@@ -0,0 +1,227 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: data_exports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueDataExports = `-- name: ClaimDueDataExports :many
+UPDATE data_exports
+SET next_attempt_at = $2::timestamptz, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM data_exports
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id
+`
+
+type ClaimDueDataExportsParams struct {
+	Limit      int32
+	LeaseUntil time.Time
+}
+
+type ClaimDueDataExportsRow struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// Pushes next_attempt_at forward so an export that is being built isn't
+// picked up again, even by another server, until lease_until
+func (q *Queries) ClaimDueDataExports(ctx context.Context, arg ClaimDueDataExportsParams) ([]ClaimDueDataExportsRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueDataExports, arg.Limit, arg.LeaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimDueDataExportsRow
+	for rows.Next() {
+		var i ClaimDueDataExportsRow
+		if err := rows.Scan(&i.ID, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeDataExport = `-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready', archive = $2, error = NULL, completed_at = NOW(), expires_at = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type CompleteDataExportParams struct {
+	ID        uuid.UUID
+	Archive   []byte
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error {
+	_, err := q.db.ExecContext(ctx, completeDataExport, arg.ID, arg.Archive, arg.ExpiresAt)
+	return err
+}
+
+const createDataExport = `-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id, status, next_attempt_at, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, 'pending', NOW(), NOW(), NOW())
+ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+RETURNING id, user_id, status, error, created_at, updated_at, completed_at, expires_at
+`
+
+type CreateDataExportRow struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+// No rows means the user already has a pending export
+func (q *Queries) CreateDataExport(ctx context.Context, userID uuid.UUID) (CreateDataExportRow, error) {
+	row := q.db.QueryRowContext(ctx, createDataExport, userID)
+	var i CreateDataExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const expireDataExports = `-- name: ExpireDataExports :execrows
+UPDATE data_exports
+SET status = 'expired', archive = NULL, updated_at = NOW()
+WHERE status = 'ready' AND expires_at <= NOW()
+`
+
+// Drops archives nobody downloaded in time, the export's status stays
+// around so polling it says what happened
+func (q *Queries) ExpireDataExports(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, expireDataExports)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const failDataExport = `-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+type FailDataExportParams struct {
+	ID    uuid.UUID
+	Error sql.NullString
+}
+
+func (q *Queries) FailDataExport(ctx context.Context, arg FailDataExportParams) error {
+	_, err := q.db.ExecContext(ctx, failDataExport, arg.ID, arg.Error)
+	return err
+}
+
+const getDataExport = `-- name: GetDataExport :one
+SELECT id, user_id, status, error, created_at, updated_at, completed_at, expires_at
+FROM data_exports
+WHERE id = $1
+`
+
+type GetDataExportRow struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+// Everything but the archive, which can be large
+func (q *Queries) GetDataExport(ctx context.Context, id uuid.UUID) (GetDataExportRow, error) {
+	row := q.db.QueryRowContext(ctx, getDataExport, id)
+	var i GetDataExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getDataExportArchive = `-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1 AND status = 'ready' AND expires_at > NOW()
+`
+
+func (q *Queries) GetDataExportArchive(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getDataExportArchive, id)
+	var archive []byte
+	err := row.Scan(&archive)
+	return archive, err
+}
+
+const getPendingDataExport = `-- name: GetPendingDataExport :one
+SELECT id, user_id, status, error, created_at, updated_at, completed_at, expires_at
+FROM data_exports
+WHERE user_id = $1 AND status = 'pending'
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetPendingDataExportRow struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+func (q *Queries) GetPendingDataExport(ctx context.Context, userID uuid.UUID) (GetPendingDataExportRow, error) {
+	row := q.db.QueryRowContext(ctx, getPendingDataExport, userID)
+	var i GetPendingDataExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
@@ -0,0 +1,93 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: login_failures.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const clearLoginFailures = `-- name: ClearLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1
+`
+
+func (q *Queries) ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, clearLoginFailures, key)
+	return err
+}
+
+const forgiveLoginFailure = `-- name: ForgiveLoginFailure :exec
+UPDATE login_failures SET failures = GREATEST(failures - 1, 0) WHERE key = $1
+`
+
+// Takes back an attempt that was counted before it turned out to be correct
+func (q *Queries) ForgiveLoginFailure(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, forgiveLoginFailure, key)
+	return err
+}
+
+const getLoginFailuresForUpdate = `-- name: GetLoginFailuresForUpdate :one
+SELECT key, failures, last_failure_at, locked_until FROM login_failures WHERE key = $1
+FOR UPDATE
+`
+
+// Held until the attempt is counted, so parallel attempts against the same
+// key are checked one at a time
+func (q *Queries) GetLoginFailuresForUpdate(ctx context.Context, key string) (LoginFailure, error) {
+	row := q.db.QueryRowContext(ctx, getLoginFailuresForUpdate, key)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailureAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const lockLoginKey = `-- name: LockLoginKey :exec
+UPDATE login_failures SET locked_until = $2 WHERE key = $1
+`
+
+type LockLoginKeyParams struct {
+	Key         string
+	LockedUntil sql.NullTime
+}
+
+func (q *Queries) LockLoginKey(ctx context.Context, arg LockLoginKeyParams) error {
+	_, err := q.db.ExecContext(ctx, lockLoginKey, arg.Key, arg.LockedUntil)
+	return err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_failures (key, failures, last_failure_at)
+VALUES ($1, 1, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    failures = CASE
+        WHEN login_failures.last_failure_at < $2 THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING key, failures, last_failure_at, locked_until
+`
+
+type RecordLoginFailureParams struct {
+	Key         string
+	ResetBefore time.Time
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginFailure, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, arg.Key, arg.ResetBefore)
+	var i LoginFailure
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailureAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
@@ -6,6 +6,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,6 +20,82 @@ type Chirp struct {
 	UserID    uuid.UUID
 }
 
+type ChirpImport struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Status        string
+	Format        string
+	Archive       []byte
+	Error         sql.NullString
+	TotalRows     int32
+	ImportedRows  int32
+	DuplicateRows int32
+	FailedRows    int32
+	RowErrors     json.RawMessage
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CompletedAt   sql.NullTime
+}
+
+type DataExport struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Status        string
+	Archive       []byte
+	Error         sql.NullString
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	CompletedAt   sql.NullTime
+	ExpiresAt     sql.NullTime
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	Email     string
+	UserID    uuid.UUID
+}
+
+type LoginFailure struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+	UserID    uuid.UUID
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	UserID    uuid.UUID
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	UserID     uuid.UUID
+}
+
 type RefreshToken struct {
 	Token     string
 	CreatedAt time.Time
@@ -28,11 +105,79 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 }
 
+type Subscription struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	Plan              string
+	Status            string
+	CurrentPeriodEnd  time.Time
+	CancelAtPeriodEnd bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type SubscriptionHistory struct {
+	ID               uuid.UUID
+	SubscriptionID   uuid.UUID
+	Event            string
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+	WebhookEventID   uuid.NullUUID
+	CreatedAt        time.Time
+}
+
 type User struct {
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+	HashedPassword  string
+	EmailVerifiedAt sql.NullTime
+	TotpSecret      sql.NullString
+	TotpEnabledAt   sql.NullTime
+	TotpLastStep    sql.NullInt64
+	Roles           []string
+	SuspendedAt     sql.NullTime
+	DeleteAfter     sql.NullTime
+	DeletedAt       sql.NullTime
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
-	Email          string
-	CreatedAt      sql.NullTime
-	UpdatedAt      sql.NullTime
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode sql.NullInt32
+	LastError      sql.NullString
+	CreatedAt      time.Time
+	DeliveredAt    sql.NullTime
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID
+	Provider    string
+	EventID     string
+	EventType   string
+	Payload     []byte
+	Status      string
+	Error       sql.NullString
+	Attempts    int32
+	ReceivedAt  time.Time
+	ClaimedAt   time.Time
+	ProcessedAt sql.NullTime
+}
+
+type WebhookSubscription struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Url       string
+	Events    []string
+	Secret    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
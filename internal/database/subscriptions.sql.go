@@ -0,0 +1,252 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: subscriptions.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createSubscriptionHistory = `-- name: CreateSubscriptionHistory :exec
+INSERT INTO subscription_history (id, subscription_id, event, plan, status, current_period_end, webhook_event_id, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, NOW())
+`
+
+type CreateSubscriptionHistoryParams struct {
+	SubscriptionID   uuid.UUID
+	Event            string
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+	WebhookEventID   uuid.NullUUID
+}
+
+func (q *Queries) CreateSubscriptionHistory(ctx context.Context, arg CreateSubscriptionHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, createSubscriptionHistory,
+		arg.SubscriptionID,
+		arg.Event,
+		arg.Plan,
+		arg.Status,
+		arg.CurrentPeriodEnd,
+		arg.WebhookEventID,
+	)
+	return err
+}
+
+const expireLapsedSubscriptions = `-- name: ExpireLapsedSubscriptions :many
+WITH expired AS (
+    UPDATE subscriptions
+    SET status = 'expired', cancel_at_period_end = FALSE, updated_at = NOW()
+    WHERE status IN ('active', 'past_due') AND current_period_end <= NOW()
+    RETURNING id, user_id, plan, status, current_period_end
+), history AS (
+    INSERT INTO subscription_history (id, subscription_id, event, plan, status, current_period_end, created_at)
+    SELECT gen_random_uuid(), id, 'expired', plan, status, current_period_end, NOW()
+    FROM expired
+)
+SELECT user_id, plan, status, current_period_end FROM expired
+`
+
+type ExpireLapsedSubscriptionsRow struct {
+	UserID           uuid.UUID
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+}
+
+func (q *Queries) ExpireLapsedSubscriptions(ctx context.Context) ([]ExpireLapsedSubscriptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, expireLapsedSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExpireLapsedSubscriptionsRow
+	for rows.Next() {
+		var i ExpireLapsedSubscriptionsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Plan,
+			&i.Status,
+			&i.CurrentPeriodEnd,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActivePlan = `-- name: GetActivePlan :one
+SELECT plan FROM subscriptions
+WHERE user_id = $1
+AND status IN ('active', 'past_due')
+AND current_period_end > NOW()
+`
+
+func (q *Queries) GetActivePlan(ctx context.Context, userID uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getActivePlan, userID)
+	var plan string
+	err := row.Scan(&plan)
+	return plan, err
+}
+
+const getChirpyRedUserIDs = `-- name: GetChirpyRedUserIDs :many
+SELECT user_id FROM subscriptions
+WHERE user_id = ANY($1::uuid[])
+AND status IN ('active', 'past_due')
+AND current_period_end > NOW()
+`
+
+// The users among ids that IsUserChirpyRed would report as red
+func (q *Queries) GetChirpyRedUserIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpyRedUserIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSubscriptionByUserID = `-- name: GetSubscriptionByUserID :one
+SELECT id, user_id, plan, status, current_period_end, cancel_at_period_end, created_at, updated_at FROM subscriptions WHERE user_id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetSubscriptionByUserID(ctx context.Context, userID uuid.UUID) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, getSubscriptionByUserID, userID)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Plan,
+		&i.Status,
+		&i.CurrentPeriodEnd,
+		&i.CancelAtPeriodEnd,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const isUserChirpyRed = `-- name: IsUserChirpyRed :one
+SELECT EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE user_id = $1
+    AND status IN ('active', 'past_due')
+    AND current_period_end > NOW()
+)
+`
+
+func (q *Queries) IsUserChirpyRed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserChirpyRed, userID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listSubscriptionHistoryForUser = `-- name: ListSubscriptionHistoryForUser :many
+SELECT subscription_history.id, subscription_history.subscription_id, subscription_history.event, subscription_history.plan, subscription_history.status, subscription_history.current_period_end, subscription_history.webhook_event_id, subscription_history.created_at FROM subscription_history
+JOIN subscriptions ON subscriptions.id = subscription_history.subscription_id
+WHERE subscriptions.user_id = $1
+ORDER BY subscription_history.created_at ASC
+`
+
+func (q *Queries) ListSubscriptionHistoryForUser(ctx context.Context, userID uuid.UUID) ([]SubscriptionHistory, error) {
+	rows, err := q.db.QueryContext(ctx, listSubscriptionHistoryForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SubscriptionHistory
+	for rows.Next() {
+		var i SubscriptionHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.SubscriptionID,
+			&i.Event,
+			&i.Plan,
+			&i.Status,
+			&i.CurrentPeriodEnd,
+			&i.WebhookEventID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertSubscription = `-- name: UpsertSubscription :one
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_end, cancel_at_period_end, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW(), NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = EXCLUDED.status,
+    current_period_end = EXCLUDED.current_period_end,
+    cancel_at_period_end = EXCLUDED.cancel_at_period_end,
+    updated_at = NOW()
+RETURNING id, user_id, plan, status, current_period_end, cancel_at_period_end, created_at, updated_at
+`
+
+type UpsertSubscriptionParams struct {
+	UserID            uuid.UUID
+	Plan              string
+	Status            string
+	CurrentPeriodEnd  time.Time
+	CancelAtPeriodEnd bool
+}
+
+func (q *Queries) UpsertSubscription(ctx context.Context, arg UpsertSubscriptionParams) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertSubscription,
+		arg.UserID,
+		arg.Plan,
+		arg.Status,
+		arg.CurrentPeriodEnd,
+		arg.CancelAtPeriodEnd,
+	)
+	var i Subscription
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Plan,
+		&i.Status,
+		&i.CurrentPeriodEnd,
+		&i.CancelAtPeriodEnd,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -10,8 +10,79 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const addUserRole = `-- name: AddUserRole :one
+UPDATE users
+SET roles = CASE WHEN $1::text = ANY(roles) THEN roles ELSE array_append(roles, $1::text) END,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
+`
+
+type AddUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) AddUserRole(ctx context.Context, arg AddUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, addUserRole, arg.Role, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users
+SET email = 'deleted-' || id || '@deleted.invalid',
+    hashed_password = '',
+    email_verified_at = NULL,
+    totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = NULL,
+    roles = '{}',
+    suspended_at = NULL,
+    delete_after = NULL,
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+// Keeps the row, so the user's chirps survive, without anything that
+// identifies or authenticates them
+func (q *Queries) AnonymizeUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUser, id)
+	return err
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+UPDATE users SET delete_after = NULL, updated_at = NOW()
+WHERE id = $1 AND delete_after IS NOT NULL
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
@@ -21,7 +92,7 @@ VALUES (
     $1,
     $2
 )
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
 `
 
 type CreateUserParams struct {
@@ -38,11 +109,54 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const deleteUserData = `-- name: DeleteUserData :exec
+WITH refresh AS (
+    DELETE FROM refresh_tokens WHERE refresh_tokens.user_id = $1
+), pats AS (
+    DELETE FROM personal_access_tokens WHERE personal_access_tokens.user_id = $1
+), resets AS (
+    DELETE FROM password_reset_tokens WHERE password_reset_tokens.user_id = $1
+), verifications AS (
+    DELETE FROM email_verification_tokens WHERE email_verification_tokens.user_id = $1
+), recovery AS (
+    DELETE FROM mfa_recovery_codes WHERE mfa_recovery_codes.user_id = $1
+), subs AS (
+    DELETE FROM subscriptions WHERE subscriptions.user_id = $1
+), webhooks AS (
+    DELETE FROM webhook_subscriptions WHERE webhook_subscriptions.user_id = $1
+), imports AS (
+    DELETE FROM chirp_imports WHERE chirp_imports.user_id = $1
+)
+DELETE FROM data_exports WHERE data_exports.user_id = $1
+`
+
+// Everything that references a user except their chirps
+func (q *Queries) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserData, userID)
+	return err
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users
 `
@@ -52,8 +166,46 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const getStats = `-- name: GetStats :one
+SELECT
+    (SELECT COUNT(*) FROM users WHERE deleted_at IS NULL) AS users,
+    (SELECT COUNT(*) FROM users WHERE email_verified_at IS NOT NULL) AS verified_users,
+    (SELECT COUNT(*) FROM users WHERE suspended_at IS NOT NULL) AS suspended_users,
+    (SELECT COUNT(*) FROM subscriptions
+        WHERE status IN ('active', 'past_due') AND current_period_end > NOW()) AS chirpy_red_users,
+    (SELECT COUNT(*) FROM chirps) AS chirps,
+    (SELECT COUNT(*) FROM chirps WHERE created_at > NOW() - INTERVAL '24 hours') AS chirps_last_day,
+    (SELECT COUNT(*) FROM refresh_tokens
+        WHERE revoked_at IS NULL AND expires_at > NOW()) AS active_sessions
+`
+
+type GetStatsRow struct {
+	Users          int64
+	VerifiedUsers  int64
+	SuspendedUsers int64
+	ChirpyRedUsers int64
+	Chirps         int64
+	ChirpsLastDay  int64
+	ActiveSessions int64
+}
+
+func (q *Queries) GetStats(ctx context.Context) (GetStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getStats)
+	var i GetStatsRow
+	err := row.Scan(
+		&i.Users,
+		&i.VerifiedUsers,
+		&i.SuspendedUsers,
+		&i.ChirpyRedUsers,
+		&i.Chirps,
+		&i.ChirpsLastDay,
+		&i.ActiveSessions,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -65,13 +217,20 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red FROM users WHERE id = $1
+SELECT id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -83,24 +242,322 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :one
-UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at FROM users WHERE id = ANY($1::uuid[])
 `
 
-type UpdateUserParams struct {
-	Email          string
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.HashedPassword,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			pq.Array(&i.Roles),
+			&i.SuspendedAt,
+			&i.DeleteAfter,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT users.id, users.email, users.created_at, users.updated_at, users.hashed_password, users.email_verified_at, users.totp_secret, users.totp_enabled_at, users.totp_last_step, users.roles, users.suspended_at, users.delete_after, users.deleted_at, EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE subscriptions.user_id = users.id
+    AND status IN ('active', 'past_due')
+    AND current_period_end > NOW()
+) AS is_chirpy_red
+FROM users
+WHERE $1::text IS NULL OR email ILIKE '%' || $1::text || '%'
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type ListUsersParams struct {
+	Search   sql.NullString
+	MaxUsers int32
+}
+
+type ListUsersRow struct {
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+	HashedPassword  string
+	EmailVerifiedAt sql.NullTime
+	TotpSecret      sql.NullString
+	TotpEnabledAt   sql.NullTime
+	TotpLastStep    sql.NullInt64
+	Roles           []string
+	SuspendedAt     sql.NullTime
+	DeleteAfter     sql.NullTime
+	DeletedAt       sql.NullTime
+	IsChirpyRed     bool
+}
+
+// Newest first. search matches part of the email, case insensitively.
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Search, arg.MaxUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.HashedPassword,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			pq.Array(&i.Roles),
+			&i.SuspendedAt,
+			&i.DeleteAfter,
+			&i.DeletedAt,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersDueForDeletion = `-- name: ListUsersDueForDeletion :many
+SELECT id FROM users
+WHERE delete_after <= NOW() AND deleted_at IS NULL
+ORDER BY delete_after
+LIMIT $1
+`
+
+func (q *Queries) ListUsersDueForDeletion(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersDueForDeletion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserForDeletion = `-- name: LockUserForDeletion :one
+SELECT id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at FROM users
+WHERE id = $1 AND delete_after <= NOW() AND deleted_at IS NULL
+FOR UPDATE SKIP LOCKED
+`
+
+// Skips users another server is deleting, or who logged in and cancelled
+// since they were listed
+func (q *Queries) LockUserForDeletion(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, lockUserForDeletion, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const removeUserRole = `-- name: RemoveUserRole :one
+UPDATE users SET roles = array_remove(roles, $1::text), updated_at = NOW()
+WHERE id = $2
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
+`
+
+type RemoveUserRoleParams struct {
+	Role string
+	ID   uuid.UUID
+}
+
+func (q *Queries) RemoveUserRole(ctx context.Context, arg RemoveUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, removeUserRole, arg.Role, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :one
+UPDATE users SET delete_after = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
+`
+
+type ScheduleUserDeletionParams struct {
+	ID          uuid.UUID
+	DeleteAfter sql.NullTime
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, scheduleUserDeletion, arg.ID, arg.DeleteAfter)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users SET hashed_password = $1, updated_at = NOW() WHERE id = $2
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
+`
+
+type UpdateUserPasswordParams struct {
 	HashedPassword string
 	ID             uuid.UUID
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HashedPassword, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -108,23 +565,30 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const updateUserIsChirpyRed = `-- name: UpdateUserIsChirpyRed :one
-UPDATE users SET is_chirpy_red = $1 WHERE id = $2
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users SET email = $1, email_verified_at = NOW(), updated_at = NOW() WHERE id = $2
+RETURNING id, email, created_at, updated_at, hashed_password, email_verified_at, totp_secret, totp_enabled_at, totp_last_step, roles, suspended_at, delete_after, deleted_at
 `
 
-type UpdateUserIsChirpyRedParams struct {
-	IsChirpyRed sql.NullBool
-	ID          uuid.UUID
+type VerifyUserEmailParams struct {
+	Email string
+	ID    uuid.UUID
 }
 
-func (q *Queries) UpdateUserIsChirpyRed(ctx context.Context, arg UpdateUserIsChirpyRedParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUserIsChirpyRed, arg.IsChirpyRed, arg.ID)
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Email, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -132,7 +596,14 @@ func (q *Queries) UpdateUserIsChirpyRed(ctx context.Context, arg UpdateUserIsChi
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		pq.Array(&i.Roles),
+		&i.SuspendedAt,
+		&i.DeleteAfter,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,217 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: webhook_events.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimWebhookEvent = `-- name: ClaimWebhookEvent :one
+UPDATE webhook_events SET status = 'processing', claimed_at = NOW()
+WHERE id = $1 AND (
+    status = 'failed'
+    OR (status = 'processing' AND claimed_at < $2::timestamptz)
+)
+RETURNING id, provider, event_id, event_type, payload, status, error, attempts, received_at, claimed_at, processed_at
+`
+
+type ClaimWebhookEventParams struct {
+	ID          uuid.UUID
+	StaleBefore time.Time
+}
+
+// Takes failed events, and events left processing since before
+// stale_before by a server that stopped before finishing them
+func (q *Queries) ClaimWebhookEvent(ctx context.Context, arg ClaimWebhookEventParams) (WebhookEvent, error) {
+	row := q.db.QueryRowContext(ctx, claimWebhookEvent, arg.ID, arg.StaleBefore)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Error,
+		&i.Attempts,
+		&i.ReceivedAt,
+		&i.ClaimedAt,
+		&i.ProcessedAt,
+	)
+	return i, err
+}
+
+const finishWebhookEvent = `-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $2, error = $3, attempts = attempts + 1, processed_at = NOW()
+WHERE id = $1
+RETURNING id, provider, event_id, event_type, payload, status, error, attempts, received_at, claimed_at, processed_at
+`
+
+type FinishWebhookEventParams struct {
+	ID     uuid.UUID
+	Status string
+	Error  sql.NullString
+}
+
+func (q *Queries) FinishWebhookEvent(ctx context.Context, arg FinishWebhookEventParams) (WebhookEvent, error) {
+	row := q.db.QueryRowContext(ctx, finishWebhookEvent, arg.ID, arg.Status, arg.Error)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Error,
+		&i.Attempts,
+		&i.ReceivedAt,
+		&i.ClaimedAt,
+		&i.ProcessedAt,
+	)
+	return i, err
+}
+
+const getWebhookEvent = `-- name: GetWebhookEvent :one
+SELECT id, provider, event_id, event_type, payload, status, error, attempts, received_at, claimed_at, processed_at FROM webhook_events WHERE id = $1
+`
+
+func (q *Queries) GetWebhookEvent(ctx context.Context, id uuid.UUID) (WebhookEvent, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookEvent, id)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Error,
+		&i.Attempts,
+		&i.ReceivedAt,
+		&i.ClaimedAt,
+		&i.ProcessedAt,
+	)
+	return i, err
+}
+
+const getWebhookEventByEventID = `-- name: GetWebhookEventByEventID :one
+SELECT id, provider, event_id, event_type, payload, status, error, attempts, received_at, claimed_at, processed_at FROM webhook_events WHERE provider = $1 AND event_id = $2
+`
+
+type GetWebhookEventByEventIDParams struct {
+	Provider string
+	EventID  string
+}
+
+func (q *Queries) GetWebhookEventByEventID(ctx context.Context, arg GetWebhookEventByEventIDParams) (WebhookEvent, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookEventByEventID, arg.Provider, arg.EventID)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Error,
+		&i.Attempts,
+		&i.ReceivedAt,
+		&i.ClaimedAt,
+		&i.ProcessedAt,
+	)
+	return i, err
+}
+
+const listWebhookEvents = `-- name: ListWebhookEvents :many
+SELECT id, provider, event_id, event_type, payload, status, error, attempts, received_at, claimed_at, processed_at FROM webhook_events
+WHERE $2::text = '' OR status = $2::text
+ORDER BY received_at DESC
+LIMIT $1
+`
+
+type ListWebhookEventsParams struct {
+	Limit  int32
+	Status string
+}
+
+func (q *Queries) ListWebhookEvents(ctx context.Context, arg ListWebhookEventsParams) ([]WebhookEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookEvents, arg.Limit, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Provider,
+			&i.EventID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Error,
+			&i.Attempts,
+			&i.ReceivedAt,
+			&i.ClaimedAt,
+			&i.ProcessedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordWebhookEvent = `-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (id, provider, event_id, event_type, payload, status, received_at, claimed_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, 'processing', NOW(), NOW())
+ON CONFLICT (provider, event_id) DO NOTHING
+RETURNING id, provider, event_id, event_type, payload, status, error, attempts, received_at, claimed_at, processed_at
+`
+
+type RecordWebhookEventParams struct {
+	Provider  string
+	EventID   string
+	EventType string
+	Payload   []byte
+}
+
+func (q *Queries) RecordWebhookEvent(ctx context.Context, arg RecordWebhookEventParams) (WebhookEvent, error) {
+	row := q.db.QueryRowContext(ctx, recordWebhookEvent,
+		arg.Provider,
+		arg.EventID,
+		arg.EventType,
+		arg.Payload,
+	)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.EventID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Error,
+		&i.Attempts,
+		&i.ReceivedAt,
+		&i.ClaimedAt,
+		&i.ProcessedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,97 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+)
+
+// PlanFree applies to users without an active subscription
+const PlanFree = "free"
+
+// Entitlements are the limits and features a plan grants
+type Entitlements struct {
+	MaxChirpLength int `json:"max_chirp_length"`
+	// EditWindowSeconds is how long after posting a chirp can be edited, 0
+	// means chirps can't be edited
+	EditWindowSeconds int `json:"edit_window_seconds"`
+	MaxMediaPerChirp  int `json:"max_media_per_chirp"`
+	// ChirpsPerHour limits how many chirps can be posted in any hour, 0
+	// means no limit
+	ChirpsPerHour   int  `json:"chirps_per_hour"`
+	ScheduledChirps bool `json:"scheduled_chirps"`
+	// Badge is shown next to the user's name, empty for none
+	Badge string `json:"badge"`
+}
+
+// EditWindow returns EditWindowSeconds as a duration
+func (e Entitlements) EditWindow() time.Duration {
+	return time.Duration(e.EditWindowSeconds) * time.Second
+}
+
+// Config maps plan names to their entitlements. It must include PlanFree.
+type Config struct {
+	Plans map[string]Entitlements `json:"plans"`
+}
+
+// DefaultConfig is used when no entitlements file is configured
+var DefaultConfig = Config{
+	Plans: map[string]Entitlements{
+		PlanFree: {
+			MaxChirpLength: 140,
+		},
+		subscriptions.PlanChirpyRed: {
+			MaxChirpLength:    1000,
+			EditWindowSeconds: 15 * 60,
+			MaxMediaPerChirp:  4,
+			ScheduledChirps:   true,
+			Badge:             "chirpy_red",
+		},
+	},
+}
+
+// Service answers what a plan is entitled to
+type Service struct {
+	plans map[string]Entitlements
+}
+
+// New validates cfg and returns a Service for it
+func New(cfg Config) (*Service, error) {
+	if _, ok := cfg.Plans[PlanFree]; !ok {
+		return nil, fmt.Errorf("entitlements are missing the %q plan", PlanFree)
+	}
+	for plan, e := range cfg.Plans {
+		if e.MaxChirpLength <= 0 {
+			return nil, fmt.Errorf("plan %q: max_chirp_length must be positive", plan)
+		}
+		if e.EditWindowSeconds < 0 || e.MaxMediaPerChirp < 0 || e.ChirpsPerHour < 0 {
+			return nil, fmt.Errorf("plan %q: limits can't be negative", plan)
+		}
+	}
+	return &Service{plans: cfg.Plans}, nil
+}
+
+// Load reads a Config from a JSON file
+func Load(path string) (*Service, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := Config{}
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return New(cfg)
+}
+
+// For returns the entitlements of plan. Unknown plans get the free plan's.
+func (s *Service) For(plan string) Entitlements {
+	if e, ok := s.plans[plan]; ok {
+		return e
+	}
+	return s.plans[PlanFree]
+}
@@ -0,0 +1,75 @@
+package entitlements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+)
+
+func TestServiceFor(t *testing.T) {
+	service, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		plan string
+		want int
+	}{
+		{name: "Free", plan: PlanFree, want: 140},
+		{name: "Chirpy Red", plan: subscriptions.PlanChirpyRed, want: 1000},
+		{name: "Unknown plan", plan: "platinum", want: 140},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := service.For(tt.plan).MaxChirpLength; got != tt.want {
+				t.Errorf("For(%q).MaxChirpLength = %d, want %d", tt.plan, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "Default", cfg: DefaultConfig, wantErr: false},
+		{name: "Missing free plan", cfg: Config{Plans: map[string]Entitlements{"pro": {MaxChirpLength: 500}}}, wantErr: true},
+		{name: "Zero chirp length", cfg: Config{Plans: map[string]Entitlements{PlanFree: {}}}, wantErr: true},
+		{name: "Negative limit", cfg: Config{Plans: map[string]Entitlements{PlanFree: {MaxChirpLength: 140, ChirpsPerHour: -1}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entitlements.json")
+	data := `{"plans": {"free": {"max_chirp_length": 200, "chirps_per_hour": 10}, "chirpy_red": {"max_chirp_length": 2000, "badge": "red"}}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := service.For(PlanFree); got.MaxChirpLength != 200 || got.ChirpsPerHour != 10 {
+		t.Errorf("For(free) = %+v", got)
+	}
+	if got := service.For(subscriptions.PlanChirpyRed); got.MaxChirpLength != 2000 || got.Badge != "red" {
+		t.Errorf("For(chirpy_red) = %+v", got)
+	}
+}
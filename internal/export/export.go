@@ -0,0 +1,127 @@
+// Package export bundles a user's data into a zip archive they can
+// download. The archive has the data as JSON, for importing elsewhere, and
+// as an HTML page for reading.
+package export
+
+import (
+	"archive/zip"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const ContentType = "application/zip"
+
+// Paths of the files in the archive
+const (
+	DataPath = "chirpy-export/data.json"
+	HTMLPath = "chirpy-export/index.html"
+)
+
+// Archive is everything Chirpy stores about a user
+type Archive struct {
+	ExportedAt           time.Time             `json:"exported_at"`
+	Profile              Profile               `json:"profile"`
+	Chirps               []Chirp               `json:"chirps"`
+	Sessions             []Session             `json:"sessions"`
+	PersonalAccessTokens []PersonalAccessToken `json:"personal_access_tokens"`
+	Webhooks             []Webhook             `json:"webhooks"`
+	SubscriptionHistory  []SubscriptionEvent   `json:"subscription_history"`
+}
+
+type Profile struct {
+	ID               uuid.UUID  `json:"id"`
+	Email            string     `json:"email"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	IsChirpyRed      bool       `json:"is_chirpy_red"`
+	Roles            []string   `json:"roles"`
+}
+
+type Chirp struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Session is a login, the refresh token itself is left out
+type Session struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// PersonalAccessToken describes a token without its secret
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Webhook describes a webhook without its signing secret
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SubscriptionEvent struct {
+	Event            string    `json:"event"`
+	Plan             string    `json:"plan"`
+	Status           string    `json:"status"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+//go:embed index.html.tmpl
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") },
+}).Parse(htmlSource))
+
+// Write writes archive to w as a zip file
+func Write(w io.Writer, archive Archive) error {
+	zw := zip.NewWriter(w)
+
+	data, err := create(zw, DataPath, archive.ExportedAt)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(archive)
+	if err != nil {
+		return err
+	}
+
+	page, err := create(zw, HTMLPath, archive.ExportedAt)
+	if err != nil {
+		return err
+	}
+	err = htmlTemplate.Execute(page, archive)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+}
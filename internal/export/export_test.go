@@ -0,0 +1,91 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, file := range zr.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	revoked := now.Add(-time.Hour)
+	archive := Archive{
+		ExportedAt: now,
+		Profile: Profile{
+			ID:        uuid.New(),
+			Email:     "user@example.com",
+			CreatedAt: now.AddDate(-1, 0, 0),
+			Roles:     []string{},
+		},
+		Chirps: []Chirp{
+			{ID: uuid.New(), Body: "<script>alert(1)</script>", CreatedAt: now},
+			{ID: uuid.New(), Body: "Hello, Chirpy", CreatedAt: now},
+		},
+		Sessions: []Session{
+			{CreatedAt: now.AddDate(0, 0, -2), ExpiresAt: now.AddDate(0, 2, 0), RevokedAt: &revoked},
+		},
+		PersonalAccessTokens: []PersonalAccessToken{},
+		Webhooks:             []Webhook{},
+		SubscriptionHistory: []SubscriptionEvent{
+			{Event: "upgraded", Plan: "chirpy_red", Status: "active", CurrentPeriodEnd: now.AddDate(0, 1, 0), CreatedAt: now},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Write(buf, archive); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	files := readZip(t, buf.Bytes())
+	if len(files) != 2 {
+		t.Fatalf("archive has %d files, want %s and %s", len(files), DataPath, HTMLPath)
+	}
+
+	got := Archive{}
+	if err := json.Unmarshal([]byte(files[DataPath]), &got); err != nil {
+		t.Fatalf("%s isn't JSON: %v", DataPath, err)
+	}
+	if got.Profile.Email != archive.Profile.Email || len(got.Chirps) != 2 || len(got.SubscriptionHistory) != 1 {
+		t.Errorf("%s = %+v, want %+v", DataPath, got, archive)
+	}
+	if got.Sessions[0].RevokedAt == nil || !got.Sessions[0].RevokedAt.Equal(revoked) {
+		t.Errorf("session revoked_at = %v, want %v", got.Sessions[0].RevokedAt, revoked)
+	}
+
+	page := files[HTMLPath]
+	for _, want := range []string{"user@example.com", "Hello, Chirpy", "&lt;script&gt;", "chirpy_red", "No personal access tokens."} {
+		if !strings.Contains(page, want) {
+			t.Errorf("%s doesn't contain %q", HTMLPath, want)
+		}
+	}
+	if strings.Contains(page, "<script>") {
+		t.Errorf("%s contains an unescaped chirp", HTMLPath)
+	}
+}
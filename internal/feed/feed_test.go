@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	published := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return Feed{
+		ID:      "urn:uuid:00000000-0000-0000-0000-000000000001",
+		Title:   "Chirps",
+		Link:    "http://localhost:8080/api/chirps",
+		Self:    "http://localhost:8080/users/1/feed.atom",
+		Updated: published.Add(time.Hour),
+		Entries: []Entry{{
+			ID:        "urn:uuid:00000000-0000-0000-0000-000000000002",
+			Title:     "Hello <world> & #friends",
+			Link:      "http://localhost:8080/api/chirps/2",
+			Author:    "someone",
+			Content:   "Hello <world> & #friends",
+			Published: published,
+			Updated:   published.Add(time.Hour),
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := testFeed().Atom()
+	if err != nil {
+		t.Fatalf("Atom() error = %v", err)
+	}
+
+	parsed := atomFeed{}
+	err = xml.Unmarshal(body, &parsed)
+	if err != nil {
+		t.Fatalf("Atom() is not valid XML: %v", err)
+	}
+	if parsed.XMLName.Space != "http://www.w3.org/2005/Atom" {
+		t.Errorf("namespace = %q", parsed.XMLName.Space)
+	}
+	if parsed.Updated != "2025-01-02T04:04:05Z" {
+		t.Errorf("updated = %q", parsed.Updated)
+	}
+	if len(parsed.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(parsed.Entries))
+	}
+	entry := parsed.Entries[0]
+	if entry.ID != "urn:uuid:00000000-0000-0000-0000-000000000002" {
+		t.Errorf("entry id = %q", entry.ID)
+	}
+	if entry.Content.Value != "Hello <world> & #friends" {
+		t.Errorf("content = %q, want the text unchanged after a round trip", entry.Content.Value)
+	}
+	if entry.Published != "2025-01-02T03:04:05Z" || entry.Updated != "2025-01-02T04:04:05Z" {
+		t.Errorf("published = %q, updated = %q", entry.Published, entry.Updated)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := testFeed().RSS()
+	if err != nil {
+		t.Fatalf("RSS() error = %v", err)
+	}
+	if !strings.Contains(string(body), `<atom:link href="http://localhost:8080/users/1/feed.atom" rel="self"`) {
+		t.Errorf("RSS() has no self link:\n%s", body)
+	}
+
+	parsed := rss{}
+	err = xml.Unmarshal(body, &parsed)
+	if err != nil {
+		t.Fatalf("RSS() is not valid XML: %v", err)
+	}
+	if parsed.Version != "2.0" {
+		t.Errorf("version = %q", parsed.Version)
+	}
+	if len(parsed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(parsed.Channel.Items))
+	}
+	item := parsed.Channel.Items[0]
+	if item.GUID.Value != "urn:uuid:00000000-0000-0000-0000-000000000002" || item.GUID.IsPermaLink {
+		t.Errorf("guid = %+v", item.GUID)
+	}
+	if item.PubDate != "Thu, 02 Jan 2025 03:04:05 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+}
+
+func TestEmptyFeed(t *testing.T) {
+	f := testFeed()
+	f.Entries = nil
+	for name, render := range map[string]func() ([]byte, error){"RSS": f.RSS, "Atom": f.Atom} {
+		body, err := render()
+		if err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		if strings.Contains(string(body), "<item>") || strings.Contains(string(body), "<entry>") {
+			t.Errorf("%s() has entries:\n%s", name, body)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package gql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+)
+
+// fakeStore keeps users and chirps in memory and counts calls
+type fakeStore struct {
+	users  []database.User
+	red    map[uuid.UUID]bool
+	chirps []database.Chirp
+	calls  map[string]int
+}
+
+func newFakeStore(users, chirpsPerUser int) *fakeStore {
+	s := &fakeStore{red: map[uuid.UUID]bool{}, calls: map[string]int{}}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range users {
+		user := database.User{ID: uuid.New(), Email: "user" + string(rune('a'+i)) + "@example.com"}
+		s.users = append(s.users, user)
+		s.red[user.ID] = i%2 == 0
+		for j := range chirpsPerUser {
+			createdAt := start.Add(time.Duration(j*users+i) * time.Minute)
+			s.chirps = append(s.chirps, database.Chirp{
+				ID:        uuid.New(),
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+				Body:      "chirp",
+				UserID:    user.ID,
+			})
+		}
+	}
+	return s
+}
+
+func (s *fakeStore) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]database.User, error) {
+	s.calls["GetUsersByIDs"]++
+	users := []database.User{}
+	for _, user := range s.users {
+		if slices.Contains(ids, user.ID) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (s *fakeStore) GetChirpyRedUserIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	s.calls["GetChirpyRedUserIDs"]++
+	red := []uuid.UUID{}
+	for _, id := range ids {
+		if s.red[id] {
+			red = append(red, id)
+		}
+	}
+	return red, nil
+}
+
+func (s *fakeStore) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	s.calls["GetChirp"]++
+	for _, chirp := range s.chirps {
+		if chirp.ID == id {
+			return chirp, nil
+		}
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+func (s *fakeStore) ListChirpsPageAsc(ctx context.Context, arg database.ListChirpsPageAscParams) ([]database.Chirp, error) {
+	s.calls["ListChirpsPage"]++
+	return s.page(arg.AuthorID, arg.AfterCreatedAt, arg.AfterID, arg.MaxChirps, false), nil
+}
+
+func (s *fakeStore) ListChirpsPageDesc(ctx context.Context, arg database.ListChirpsPageDescParams) ([]database.Chirp, error) {
+	s.calls["ListChirpsPage"]++
+	return s.page(arg.AuthorID, arg.AfterCreatedAt, arg.AfterID, arg.MaxChirps, true), nil
+}
+
+func (s *fakeStore) page(authorID uuid.NullUUID, afterCreatedAt sql.NullTime, afterID uuid.NullUUID, limit int32, desc bool) []database.Chirp {
+	compare := func(a, b database.Chirp) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	}
+	sorted := slices.Clone(s.chirps)
+	slices.SortFunc(sorted, compare)
+	if desc {
+		slices.Reverse(sorted)
+	}
+
+	page := []database.Chirp{}
+	after := database.Chirp{CreatedAt: afterCreatedAt.Time, ID: afterID.UUID}
+	for _, chirp := range sorted {
+		if authorID.Valid && chirp.UserID != authorID.UUID {
+			continue
+		}
+		if afterCreatedAt.Valid && ((!desc && compare(chirp, after) <= 0) || (desc && compare(chirp, after) >= 0)) {
+			continue
+		}
+		if len(page) == int(limit) {
+			break
+		}
+		page = append(page, chirp)
+	}
+	return page
+}
+
+func execute(t *testing.T, server *Server, query string, variables map[string]any, viewer uuid.UUID) map[string]any {
+	t.Helper()
+	result := server.Execute(context.Background(), Request{Query: query, Variables: variables}, viewer)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Execute() errors = %v", result.Errors)
+	}
+	raw, _ := json.Marshal(result.Data)
+	data := map[string]any{}
+	json.Unmarshal(raw, &data)
+	return data
+}
+
+func TestAuthorsAreBatched(t *testing.T) {
+	store := newFakeStore(5, 10)
+	server, err := NewServer(store, DefaultLimits)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	data := execute(t, server, `{
+		chirps(first: 50) {
+			edges { node { id author { id isChirpyRed } } }
+		}
+	}`, nil, uuid.Nil)
+
+	edges := data["chirps"].(map[string]any)["edges"].([]any)
+	if len(edges) != 50 {
+		t.Fatalf("got %d chirps, want 50", len(edges))
+	}
+	if store.calls["GetUsersByIDs"] != 1 {
+		t.Errorf("GetUsersByIDs called %d times for 50 authors, want 1", store.calls["GetUsersByIDs"])
+	}
+	if store.calls["GetChirpyRedUserIDs"] != 1 {
+		t.Errorf("GetChirpyRedUserIDs called %d times for 50 authors, want 1", store.calls["GetChirpyRedUserIDs"])
+	}
+	for _, edge := range edges {
+		node := edge.(map[string]any)["node"].(map[string]any)
+		if node["author"].(map[string]any)["id"] == "" {
+			t.Errorf("chirp %v has no author", node["id"])
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	store := newFakeStore(2, 5)
+	server, err := NewServer(store, DefaultLimits)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	query := `query Page($after: String, $order: ChirpOrder) {
+		chirps(first: 4, after: $after, order: $order) {
+			edges { cursor node { id } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	for _, order := range []string{"ASC", "DESC"} {
+		seen := []string{}
+		var after any
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatalf("%s: more pages than expected", order)
+			}
+			data := execute(t, server, query, map[string]any{"after": after, "order": order}, uuid.Nil)
+			connection := data["chirps"].(map[string]any)
+			for _, edge := range connection["edges"].([]any) {
+				seen = append(seen, edge.(map[string]any)["node"].(map[string]any)["id"].(string))
+			}
+			pageInfo := connection["pageInfo"].(map[string]any)
+			if pageInfo["hasNextPage"] != true {
+				break
+			}
+			after = pageInfo["endCursor"]
+		}
+
+		want := []string{}
+		for _, chirp := range store.page(uuid.NullUUID{}, sql.NullTime{}, uuid.NullUUID{}, 100, order == "DESC") {
+			want = append(want, chirp.ID.String())
+		}
+		if !slices.Equal(seen, want) {
+			t.Errorf("%s: paged through %v, want %v", order, seen, want)
+		}
+	}
+}
+
+func TestViewer(t *testing.T) {
+	store := newFakeStore(2, 1)
+	server, err := NewServer(store, DefaultLimits)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	viewer, other := store.users[0], store.users[1]
+	query := `query($id: ID!) {
+		viewer { email chirps { edges { node { id } } } }
+		user(id: $id) { email }
+	}`
+
+	data := execute(t, server, query, map[string]any{"id": other.ID.String()}, viewer.ID)
+	gotViewer := data["viewer"].(map[string]any)
+	if gotViewer["email"] != viewer.Email {
+		t.Errorf("viewer email = %v, want %v", gotViewer["email"], viewer.Email)
+	}
+	if edges := gotViewer["chirps"].(map[string]any)["edges"].([]any); len(edges) != 1 {
+		t.Errorf("viewer has %d chirps, want 1", len(edges))
+	}
+	if email := data["user"].(map[string]any)["email"]; email != nil {
+		t.Errorf("another user's email = %v, want null", email)
+	}
+
+	data = execute(t, server, `{ viewer { id } }`, nil, uuid.Nil)
+	if data["viewer"] != nil {
+		t.Errorf("anonymous viewer = %v, want null", data["viewer"])
+	}
+}
+
+func TestLimits(t *testing.T) {
+	server, err := NewServer(newFakeStore(1, 1), Limits{MaxDepth: 6, MaxComplexity: 500})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	// 100 chirps per level, ten levels down, is far more than an int holds
+	deeplyNested := "{ " + strings.Repeat("chirps(first: 100) { edges { node { author { ", 10) + "id" + strings.Repeat(" } } } }", 10) + " }"
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		wantErr   string
+	}{
+		{
+			name:  "Within limits",
+			query: `{ chirps(first: 20) { edges { node { id author { id } } } } }`,
+		},
+		{
+			name:    "Too deep",
+			query:   `{ chirps { edges { node { author { chirps { edges { node { id } } } } } } } }`,
+			wantErr: "nested 8 levels deep",
+		},
+		{
+			name:    "Too deep through fragments",
+			query:   `{ chirps { edges { node { ...Author } } } } fragment Author on Chirp { author { chirps { edges { node { id } } } } }`,
+			wantErr: "nested 8 levels deep",
+		},
+		{
+			name:    "Too complex",
+			query:   `{ chirps(first: 100) { edges { node { id body author { id } } } } }`,
+			wantErr: "complexity",
+		},
+		{
+			name:      "Page size from a variable",
+			query:     `query($n: Int) { chirps(first: $n) { edges { node { id body author { id } } } } }`,
+			variables: map[string]any{"n": float64(100)},
+			wantErr:   "complexity",
+		},
+		{
+			name:    "Unset variable counts as the largest page",
+			query:   `query($n: Int = 5) { chirps(first: $n) { edges { node { id body author { id } } } } }`,
+			wantErr: "complexity",
+		},
+		{
+			name:    "Page too large",
+			query:   `{ chirps(first: 1000) { edges { node { id } } } }`,
+			wantErr: "first must be between 1 and 100",
+		},
+		{
+			name:    "Empty page",
+			query:   `{ chirps(first: 0) { edges { node { id } } } }`,
+			wantErr: "first must be between 1 and 100",
+		},
+		{
+			name:      "Page too large from a variable",
+			query:     `query($n: Int) { chirps(first: $n) { edges { node { id } } } }`,
+			variables: map[string]any{"n": float64(1e20)},
+			wantErr:   "first must be between 1 and 100",
+		},
+		{
+			name:      "Fractional page size from a variable",
+			query:     `query($n: Int) { chirps(first: $n) { edges { node { id } } } }`,
+			variables: map[string]any{"n": 2.5},
+			wantErr:   "first must be between 1 and 100",
+		},
+		{
+			name:    "Deeply nested lists don't overflow",
+			query:   deeplyNested,
+			wantErr: "nested",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := server.Execute(context.Background(), Request{
+				Query:     tt.query,
+				Variables: tt.variables,
+			}, uuid.Nil)
+			if tt.wantErr == "" {
+				if len(result.Errors) > 0 {
+					t.Errorf("Execute() errors = %v", result.Errors)
+				}
+				return
+			}
+			if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Message, tt.wantErr) {
+				t.Errorf("Execute() errors = %v, want %q", result.Errors, tt.wantErr)
+			}
+			if result.Data != nil {
+				t.Errorf("Execute() ran a query over the limits")
+			}
+		})
+	}
+}
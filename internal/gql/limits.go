@@ -0,0 +1,149 @@
+package gql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound how expensive a query can be before it runs
+type Limits struct {
+	// MaxDepth is how deeply fields can be nested
+	MaxDepth int
+	// MaxComplexity bounds the estimated number of fields resolved. Each
+	// field costs 1, and the fields below a paginated field count once per
+	// item requested with first.
+	MaxComplexity int
+}
+
+var DefaultLimits = Limits{
+	MaxDepth:      10,
+	MaxComplexity: 2000,
+}
+
+// complexityCeiling caps the complexity measured for a selection set, so
+// deeply nested lists can't overflow it before the depth is checked
+const complexityCeiling = math.MaxInt32
+
+var errInvalidFirst = fmt.Errorf("first must be between 1 and %d", maxPageSize)
+
+type limitChecker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// checkLimits returns an error if any operation in doc is over limits. doc
+// must already be valid, fragments can't form cycles.
+func checkLimits(doc *ast.Document, variables map[string]any, limits Limits) error {
+	c := limitChecker{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		depth, complexity, err := c.measure(operation.SelectionSet)
+		if err != nil {
+			return err
+		}
+		if depth > limits.MaxDepth {
+			return fmt.Errorf("query is nested %d levels deep, the limit is %d", depth, limits.MaxDepth)
+		}
+		if complexity > limits.MaxComplexity {
+			return fmt.Errorf("query complexity is %d, the limit is %d", complexity, limits.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+// measure returns the depth and complexity of a selection set, complexity
+// is at most complexityCeiling
+func (c limitChecker) measure(set *ast.SelectionSet) (depth, complexity int, err error) {
+	if set == nil {
+		return 0, 0, nil
+	}
+	for _, selection := range set.Selections {
+		var childDepth, childComplexity int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity, err = c.measure(selection.SelectionSet)
+			if err != nil {
+				return 0, 0, err
+			}
+			multiplier, err := c.multiplier(selection)
+			if err != nil {
+				return 0, 0, err
+			}
+			childDepth++
+			childComplexity = 1 + childComplexity*multiplier
+		case *ast.InlineFragment:
+			childDepth, childComplexity, err = c.measure(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				childDepth, childComplexity, err = c.measure(fragment.SelectionSet)
+			}
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		depth = max(depth, childDepth)
+		complexity = min(complexity+childComplexity, complexityCeiling)
+	}
+	return depth, complexity, nil
+}
+
+// multiplier is how many times a field's children are resolved, the first
+// argument for paginated fields. first must be a whole number of items the
+// field would accept.
+func (c limitChecker) multiplier(field *ast.Field) (int, error) {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		n := 0
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			parsed, err := strconv.Atoi(value.Value)
+			if err != nil {
+				return 0, errInvalidFirst
+			}
+			n = parsed
+		case *ast.Variable:
+			switch v := c.variables[value.Name.Value].(type) {
+			case nil:
+				// Unset, the variable's default can be anything up to the
+				// largest page
+				return maxPageSize, nil
+			case float64:
+				if v != math.Trunc(v) || v < 1 || v > maxPageSize {
+					return 0, errInvalidFirst
+				}
+				n = int(v)
+			case int:
+				n = v
+			default:
+				return 0, errInvalidFirst
+			}
+		default:
+			return 0, errInvalidFirst
+		}
+		if n < 1 || n > maxPageSize {
+			return 0, errInvalidFirst
+		}
+		return n, nil
+	}
+	if field.Name.Value == "chirps" {
+		return defaultPageSize, nil
+	}
+	return 1, nil
+}
@@ -0,0 +1,84 @@
+package gql
+
+import (
+	"context"
+	"sync"
+)
+
+// Loader batches the keys requested while one level of a query resolves
+// into a single fetch. Load returns a thunk, graphql-go runs a level's
+// resolvers before any of their thunks, so by the time the first thunk runs
+// every key of the level has been queued. Results are cached for the life
+// of the loader, which is one request.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	cache   map[K]V
+	pending *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	result map[K]V
+	err    error
+}
+
+// NewLoader returns a Loader that fetches with fetch. Keys missing from the
+// map fetch returns are reported as not found.
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, cache: map[K]V{}}
+}
+
+// Load queues key and returns a function that waits for its value
+func (l *Loader[K, V]) Load(ctx context.Context, key K) func() (V, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if value, ok := l.cache[key]; ok {
+		return func() (V, bool, error) { return value, true, nil }
+	}
+	if l.pending == nil {
+		l.pending = &batch[K, V]{}
+	}
+	b := l.pending
+	b.keys = append(b.keys, key)
+
+	return func() (V, bool, error) {
+		b.once.Do(func() { l.run(ctx, b) })
+		value, ok := b.result[key]
+		return value, ok, b.err
+	}
+}
+
+func (l *Loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	keys := uniqueKeys(b.keys)
+	l.mu.Unlock()
+
+	b.result, b.err = l.fetch(ctx, keys)
+	if b.err != nil {
+		return
+	}
+	l.mu.Lock()
+	for key, value := range b.result {
+		l.cache[key] = value
+	}
+	l.mu.Unlock()
+}
+
+func uniqueKeys[K comparable](keys []K) []K {
+	seen := map[K]bool{}
+	unique := make([]K, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
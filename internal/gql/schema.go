@@ -0,0 +1,433 @@
+package gql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// Store is the data the schema reads, *database.Queries implements it
+type Store interface {
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]database.User, error)
+	GetChirpyRedUserIDs(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	ListChirpsPageAsc(ctx context.Context, arg database.ListChirpsPageAscParams) ([]database.Chirp, error)
+	ListChirpsPageDesc(ctx context.Context, arg database.ListChirpsPageDescParams) ([]database.Chirp, error)
+}
+
+// Request is a GraphQL request as clients send it
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Server executes queries against the schema
+type Server struct {
+	schema graphql.Schema
+	store  Store
+	limits Limits
+}
+
+type contextKey struct{}
+
+// requestState is shared by the resolvers of one request
+type requestState struct {
+	viewer uuid.UUID
+	users  *Loader[uuid.UUID, database.User]
+	red    *Loader[uuid.UUID, bool]
+}
+
+func stateFrom(ctx context.Context) *requestState {
+	return ctx.Value(contextKey{}).(*requestState)
+}
+
+// NewServer builds the schema over store
+func NewServer(store Store, limits Limits) (*Server, error) {
+	s := &Server{store: store, limits: limits}
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Execute runs req for viewer, uuid.Nil for anonymous requests. Queries
+// that are invalid or over the limits aren't run.
+func (s *Server) Execute(ctx context.Context, req Request, viewer uuid.UUID) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	validation := graphql.ValidateDocument(&s.schema, doc, graphql.SpecifiedRules)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	err = checkLimits(doc, req.Variables, s.limits)
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	state := &requestState{
+		viewer: viewer,
+		users:  NewLoader(s.fetchUsers),
+		red:    NewLoader(s.fetchChirpyRed),
+	}
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(ctx, contextKey{}, state),
+	})
+}
+
+func (s *Server) fetchUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]database.User, error) {
+	users, err := s.store.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]database.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	return byID, nil
+}
+
+func (s *Server) fetchChirpyRed(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	red, err := s.store.GetChirpyRedUserIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		byID[id] = false
+	}
+	for _, id := range red {
+		byID[id] = true
+	}
+	return byID, nil
+}
+
+// loadUser resolves to a user, or nil if there's no such user
+func loadUser(p graphql.ResolveParams, id uuid.UUID) func() (any, error) {
+	thunk := stateFrom(p.Context).users.Load(p.Context, id)
+	return func() (any, error) {
+		user, ok, err := thunk()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return user, nil
+	}
+}
+
+func (s *Server) buildSchema() (graphql.Schema, error) {
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+	order := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ChirpOrder",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  &graphql.EnumValueConfig{Value: "asc", Description: "Oldest first"},
+			"DESC": &graphql.EnumValueConfig{Value: "desc", Description: "Newest first"},
+		},
+	})
+	pageArgs := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: defaultPageSize,
+			Description:  fmt.Sprintf("How many chirps to return, at most %d", maxPageSize),
+		},
+		"after": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "The endCursor of the previous page",
+		},
+		"order": &graphql.ArgumentConfig{Type: order, DefaultValue: "asc"},
+	}
+
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "User",
+		Fields: graphql.Fields{},
+	})
+	chirp := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Chirp",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: chirpField(func(c database.Chirp) any { return c.ID.String() })},
+			"body":      &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: chirpField(func(c database.Chirp) any { return c.Body })},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: chirpField(func(c database.Chirp) any { return c.CreatedAt })},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: chirpField(func(c database.Chirp) any { return c.UpdatedAt })},
+			"author": &graphql.Field{
+				Type: graphql.NewNonNull(user),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return loadUser(p, p.Source.(database.Chirp).UserID), nil
+				},
+			},
+		},
+	})
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ChirpEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(chirp)},
+		},
+	})
+	connection := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ChirpConnection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge)))},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfo)},
+		},
+	})
+
+	user.AddFieldConfig("id", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.ID),
+		Resolve: userField(func(u database.User) any { return u.ID.String() }),
+	})
+	user.AddFieldConfig("email", &graphql.Field{
+		Type:        graphql.String,
+		Description: "Only visible to the user themselves",
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			u := p.Source.(database.User)
+			if u.ID != stateFrom(p.Context).viewer {
+				return nil, nil
+			}
+			return u.Email, nil
+		},
+	})
+	user.AddFieldConfig("createdAt", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.DateTime),
+		Resolve: userField(func(u database.User) any { return u.CreatedAt.Time }),
+	})
+	user.AddFieldConfig("isChirpyRed", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Boolean),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			thunk := stateFrom(p.Context).red.Load(p.Context, p.Source.(database.User).ID)
+			return func() (any, error) {
+				red, _, err := thunk()
+				return red, err
+			}, nil
+		},
+	})
+	user.AddFieldConfig("chirps", &graphql.Field{
+		Type: graphql.NewNonNull(connection),
+		Args: pageArgs,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			authorID := p.Source.(database.User).ID
+			return s.chirpsPage(p, uuid.NullUUID{UUID: authorID, Valid: true})
+		},
+	})
+
+	chirpsArgs := graphql.FieldConfigArgument{
+		"authorId": &graphql.ArgumentConfig{Type: graphql.ID},
+	}
+	for name, arg := range pageArgs {
+		chirpsArgs[name] = arg
+	}
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"viewer": &graphql.Field{
+				Type:        user,
+				Description: "The authenticated user, null for anonymous requests",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					viewer := stateFrom(p.Context).viewer
+					if viewer == uuid.Nil {
+						return nil, nil
+					}
+					return loadUser(p, viewer), nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: user,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return loadUser(p, id), nil
+				},
+			},
+			"chirp": &graphql.Field{
+				Type: chirp,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					c, err := s.store.GetChirp(p.Context, id)
+					if errors.Is(err, sql.ErrNoRows) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return c, nil
+				},
+			},
+			"chirps": &graphql.Field{
+				Type: graphql.NewNonNull(connection),
+				Args: chirpsArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					authorID := uuid.NullUUID{}
+					if raw, ok := p.Args["authorId"]; ok && raw != nil {
+						id, err := parseID(raw)
+						if err != nil {
+							return nil, err
+						}
+						authorID = uuid.NullUUID{UUID: id, Valid: true}
+					}
+					return s.chirpsPage(p, authorID)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+type connectionResult struct {
+	Edges    []edgeResult   `json:"edges"`
+	PageInfo pageInfoResult `json:"pageInfo"`
+}
+
+type edgeResult struct {
+	Cursor string         `json:"cursor"`
+	Node   database.Chirp `json:"node"`
+}
+
+type pageInfoResult struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+// chirpsPage fetches a page of chirps for the first, after and order
+// arguments. It asks for one more chirp than requested to know whether
+// there's a next page.
+func (s *Server) chirpsPage(p graphql.ResolveParams, authorID uuid.NullUUID) (any, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxPageSize {
+		return nil, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+	}
+	afterCreatedAt, afterID := sql.NullTime{}, uuid.NullUUID{}
+	if after, _ := p.Args["after"].(string); after != "" {
+		createdAt, id, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		afterCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+		afterID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	var chirps []database.Chirp
+	var err error
+	if p.Args["order"] == "desc" {
+		chirps, err = s.store.ListChirpsPageDesc(p.Context, database.ListChirpsPageDescParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			MaxChirps:      int32(first + 1),
+		})
+	} else {
+		chirps, err = s.store.ListChirpsPageAsc(p.Context, database.ListChirpsPageAscParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			MaxChirps:      int32(first + 1),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := connectionResult{Edges: []edgeResult{}}
+	if len(chirps) > first {
+		chirps = chirps[:first]
+		result.PageInfo.HasNextPage = true
+	}
+	for _, c := range chirps {
+		result.Edges = append(result.Edges, edgeResult{Cursor: encodeCursor(c), Node: c})
+	}
+	if len(result.Edges) > 0 {
+		result.PageInfo.EndCursor = &result.Edges[len(result.Edges)-1].Cursor
+	}
+	return result, nil
+}
+
+// Cursors are opaque to clients, they hold the position of a chirp in the
+// created_at, id order
+func encodeCursor(c database.Chirp) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID.String()))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	invalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	return time.Unix(0, unixNano), parsed, nil
+}
+
+func parseID(raw any) (uuid.UUID, error) {
+	s, _ := raw.(string)
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid ID %q", s)
+	}
+	return id, nil
+}
+
+func chirpField(get func(database.Chirp) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(database.Chirp)), nil
+	}
+}
+
+func userField(get func(database.User) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(database.User)), nil
+	}
+}
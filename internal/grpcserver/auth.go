@@ -0,0 +1,84 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/chirpypb"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authenticatedMethods need an access JWT, or a personal access token with
+// the scope the matching HTTP endpoint needs. Every other method is public.
+var authenticatedMethods = map[string]auth.Scope{
+	chirpypb.UsersService_GetCurrentUser_FullMethodName: auth.ScopeChirpsRead,
+	chirpypb.ChirpsService_CreateChirp_FullMethodName:   auth.ScopeChirpsWrite,
+	chirpypb.ChirpsService_DeleteChirp_FullMethodName:   auth.ScopeChirpsWrite,
+}
+
+type userIDKey struct{}
+
+// UserID returns the authenticated caller of a method that needs an access
+// token
+func UserID(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+type authenticator struct {
+	validateToken ValidateToken
+}
+
+func (a authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate adds the caller to ctx when method needs an access token
+func (a authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := authenticatedMethods[method]
+	if !ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Couldn't find token")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return nil, status.Error(codes.Unauthenticated, "Couldn't find token")
+	}
+	userID, err := a.validateToken(ctx, token, scope)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return context.WithValue(ctx, userIDKey{}, userID), nil
+}
+
+// authenticatedStream carries the context with the caller to a stream
+// handler
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
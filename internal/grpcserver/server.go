@@ -0,0 +1,87 @@
+// Package grpcserver serves the Users, Auth and Chirps gRPC services. The
+// services only translate between protobuf messages and a Backend, which
+// runs the same logic as the HTTP handlers.
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/chirpypb"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+)
+
+// User is an account as the services return it
+type User struct {
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	IsChirpyRed     bool
+	IsEmailVerified bool
+}
+
+// Chirp is a chirp as the services return it
+type Chirp struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Body      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Session is the result of a login. Users with two-factor enabled only get
+// an MFAToken.
+type Session struct {
+	User         User
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+}
+
+// Backend runs the business logic behind the services. Errors that
+// implement GRPCStatus are returned to the caller with their code, anything
+// else is reported as an internal error.
+type Backend interface {
+	CreateUser(ctx context.Context, email, password string) (User, error)
+	GetUser(ctx context.Context, id uuid.UUID) (User, error)
+
+	// Login checks a password, ip is the caller's address for throttling
+	Login(ctx context.Context, email, password, ip string) (Session, error)
+	// LoginMFA exchanges the MFAToken from Login and a second factor code
+	// for a session
+	LoginMFA(ctx context.Context, mfaToken, code, ip string) (Session, error)
+	RefreshToken(ctx context.Context, refreshToken string) (string, error)
+	RevokeToken(ctx context.Context, refreshToken string) error
+
+	CreateChirp(ctx context.Context, userID uuid.UUID, body string) (Chirp, error)
+	GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
+	ListChirps(ctx context.Context, authorID uuid.NullUUID, desc bool) ([]Chirp, error)
+	DeleteChirp(ctx context.Context, userID, id uuid.UUID) error
+	// WatchChirps calls send with every new chirp, by authorID when it's
+	// set, until ctx is done or send fails
+	WatchChirps(ctx context.Context, authorID uuid.NullUUID, send func(Chirp) error) error
+}
+
+// ValidateToken returns the user an access JWT or personal access token
+// belongs to. Personal access tokens must have been granted scope. Errors
+// are returned to the caller like Backend errors.
+type ValidateToken func(ctx context.Context, token string, scope auth.Scope) (uuid.UUID, error)
+
+// New returns a gRPC server with every service registered. Calls to methods
+// that need a user must carry an access JWT or personal access token in
+// the authorization metadata, as "Bearer <token>".
+func New(backend Backend, validateToken ValidateToken, opts ...grpc.ServerOption) *grpc.Server {
+	authenticator := authenticator{validateToken: validateToken}
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(authenticator.unary),
+		grpc.ChainStreamInterceptor(authenticator.stream),
+	)
+	server := grpc.NewServer(opts...)
+	chirpypb.RegisterUsersServiceServer(server, &usersService{backend: backend})
+	chirpypb.RegisterAuthServiceServer(server, &authService{backend: backend})
+	chirpypb.RegisterChirpsServiceServer(server, &chirpsService{backend: backend})
+	return server
+}
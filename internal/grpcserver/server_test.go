@@ -0,0 +1,362 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/chirpypb"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	validToken = "valid-token"
+	// readOnlyToken is a personal access token with only chirps:read
+	readOnlyToken = "chirpy_pat_read-only"
+)
+
+var errBackendFailure = errors.New("connection refused")
+
+type fakeBackend struct {
+	user   User
+	mu     sync.Mutex
+	chirps []Chirp
+	// watching is closed once WatchChirps has subscribed
+	watching chan struct{}
+	live     chan Chirp
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		user:     User{ID: uuid.New(), Email: "user@example.com", IsChirpyRed: true},
+		watching: make(chan struct{}),
+		live:     make(chan Chirp),
+	}
+}
+
+func (b *fakeBackend) CreateUser(ctx context.Context, email, password string) (User, error) {
+	if email == "" {
+		return User{}, status.Error(codes.InvalidArgument, "Invalid email address")
+	}
+	return User{ID: uuid.New(), Email: email}, nil
+}
+
+func (b *fakeBackend) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	if id != b.user.ID {
+		return User{}, status.Error(codes.NotFound, "User not found")
+	}
+	return b.user, nil
+}
+
+func (b *fakeBackend) Login(ctx context.Context, email, password, ip string) (Session, error) {
+	if password != "hunter2" {
+		return Session{}, status.Error(codes.Unauthenticated, "Incorrect email or password")
+	}
+	if email == "mfa@example.com" {
+		return Session{MFAToken: "mfa-token"}, nil
+	}
+	return Session{User: b.user, AccessToken: validToken, RefreshToken: "refresh-" + ip}, nil
+}
+
+func (b *fakeBackend) LoginMFA(ctx context.Context, mfaToken, code, ip string) (Session, error) {
+	if mfaToken != "mfa-token" || code != "123456" {
+		return Session{}, status.Error(codes.Unauthenticated, "Invalid code")
+	}
+	return Session{User: b.user, AccessToken: validToken, RefreshToken: "refresh-" + ip}, nil
+}
+
+func (b *fakeBackend) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
+	return validToken, nil
+}
+
+func (b *fakeBackend) RevokeToken(ctx context.Context, refreshToken string) error {
+	return errBackendFailure
+}
+
+func (b *fakeBackend) CreateChirp(ctx context.Context, userID uuid.UUID, body string) (Chirp, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	chirp := Chirp{ID: uuid.New(), UserID: userID, Body: body, CreatedAt: time.Now()}
+	b.chirps = append(b.chirps, chirp)
+	return chirp, nil
+}
+
+func (b *fakeBackend) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, chirp := range b.chirps {
+		if chirp.ID == id {
+			return chirp, nil
+		}
+	}
+	return Chirp{}, status.Error(codes.NotFound, "Error getting chirp")
+}
+
+func (b *fakeBackend) ListChirps(ctx context.Context, authorID uuid.NullUUID, desc bool) ([]Chirp, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.chirps, nil
+}
+
+func (b *fakeBackend) DeleteChirp(ctx context.Context, userID, id uuid.UUID) error {
+	return status.Error(codes.PermissionDenied, "You are not the owner of this chirp")
+}
+
+func (b *fakeBackend) WatchChirps(ctx context.Context, authorID uuid.NullUUID, send func(Chirp) error) error {
+	close(b.watching)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case chirp := <-b.live:
+			if authorID.Valid && chirp.UserID != authorID.UUID {
+				continue
+			}
+			if err := send(chirp); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// backendUserID is who validToken belongs to
+var backendUserID = uuid.New()
+
+func validateToken(ctx context.Context, token string, scope auth.Scope) (uuid.UUID, error) {
+	switch {
+	case token == validToken:
+		return backendUserID, nil
+	case token == readOnlyToken && scope == auth.ScopeChirpsRead:
+		return backendUserID, nil
+	case token == readOnlyToken:
+		return uuid.Nil, status.Errorf(codes.PermissionDenied, "Token is missing the %s scope", scope)
+	}
+	return uuid.Nil, status.Error(codes.Unauthenticated, "Invalid JWT")
+}
+
+// dial starts a server over an in-memory listener and returns a connection
+// to it
+func dial(t *testing.T, backend Backend) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := New(backend, validateToken)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthentication(t *testing.T) {
+	backend := newFakeBackend()
+	backend.user.ID = backendUserID
+	users := chirpypb.NewUsersServiceClient(dial(t, backend))
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{name: "Valid token", ctx: withToken(validToken), wantCode: codes.OK},
+		{name: "No token", ctx: context.Background(), wantCode: codes.Unauthenticated},
+		{name: "Invalid token", ctx: withToken("forged"), wantCode: codes.Unauthenticated},
+		{name: "Personal access token with the scope", ctx: withToken(readOnlyToken), wantCode: codes.OK},
+		{
+			name:     "Not a bearer token",
+			ctx:      metadata.AppendToOutgoingContext(context.Background(), "authorization", validToken),
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := users.GetCurrentUser(tt.ctx, &chirpypb.GetCurrentUserRequest{})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GetCurrentUser() error = %v, want code %v", err, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && resp.GetUser().GetId() != backendUserID.String() {
+				t.Errorf("GetCurrentUser() id = %s, want %s", resp.GetUser().GetId(), backendUserID)
+			}
+		})
+	}
+
+	// Public methods don't need a token
+	_, err := users.CreateUser(context.Background(), &chirpypb.CreateUserRequest{Email: "new@example.com", Password: "hunter2"})
+	if err != nil {
+		t.Errorf("CreateUser() error = %v", err)
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	backend := newFakeBackend()
+	auth := chirpypb.NewAuthServiceClient(dial(t, backend))
+
+	login, err := auth.Login(context.Background(), &chirpypb.LoginRequest{Email: "mfa@example.com", Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if !login.GetMfaRequired() || login.GetAccessToken() != "" {
+		t.Fatalf("Login() = %v, want only an MFA token", login)
+	}
+
+	_, err = auth.LoginMFA(context.Background(), &chirpypb.LoginMFARequest{MfaToken: login.GetMfaToken(), Code: "000000"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("LoginMFA() with a wrong code error = %v, want Unauthenticated", err)
+	}
+	resp, err := auth.LoginMFA(context.Background(), &chirpypb.LoginMFARequest{MfaToken: login.GetMfaToken(), Code: "123456"})
+	if err != nil {
+		t.Fatalf("LoginMFA() error = %v", err)
+	}
+	if resp.GetAccessToken() != validToken || resp.GetUser().GetId() != backend.user.ID.String() {
+		t.Errorf("LoginMFA() = %v, want a session for %s", resp, backend.user.ID)
+	}
+}
+
+func TestChirps(t *testing.T) {
+	conn := dial(t, newFakeBackend())
+	chirps := chirpypb.NewChirpsServiceClient(conn)
+
+	created, err := chirps.CreateChirp(withToken(validToken), &chirpypb.CreateChirpRequest{Body: "Hello, gRPC"})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	if created.GetChirp().GetUserId() != backendUserID.String() {
+		t.Errorf("CreateChirp() author = %s, want the caller %s", created.GetChirp().GetUserId(), backendUserID)
+	}
+	_, err = chirps.CreateChirp(context.Background(), &chirpypb.CreateChirpRequest{Body: "Anonymous"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("CreateChirp() without a token error = %v, want Unauthenticated", err)
+	}
+	_, err = chirps.CreateChirp(withToken(readOnlyToken), &chirpypb.CreateChirpRequest{Body: "Read only"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("CreateChirp() with a chirps:read token error = %v, want PermissionDenied", err)
+	}
+
+	got, err := chirps.GetChirp(context.Background(), &chirpypb.GetChirpRequest{Id: created.GetChirp().GetId()})
+	if err != nil {
+		t.Fatalf("GetChirp() error = %v", err)
+	}
+	if got.GetChirp().GetBody() != "Hello, gRPC" {
+		t.Errorf("GetChirp() body = %q, want %q", got.GetChirp().GetBody(), "Hello, gRPC")
+	}
+
+	list, err := chirps.ListChirps(context.Background(), &chirpypb.ListChirpsRequest{Sort: chirpypb.SortOrder_SORT_ORDER_DESC})
+	if err != nil {
+		t.Fatalf("ListChirps() error = %v", err)
+	}
+	if len(list.GetChirps()) != 1 {
+		t.Errorf("ListChirps() returned %d chirps, want 1", len(list.GetChirps()))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	conn := dial(t, newFakeBackend())
+	chirps := chirpypb.NewChirpsServiceClient(conn)
+	auth := chirpypb.NewAuthServiceClient(conn)
+
+	tests := []struct {
+		name        string
+		call        func() error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{
+			name: "Invalid ID",
+			call: func() error {
+				_, err := chirps.GetChirp(context.Background(), &chirpypb.GetChirpRequest{Id: "not-a-uuid"})
+				return err
+			},
+			wantCode:    codes.InvalidArgument,
+			wantMessage: "Invalid chirp ID",
+		},
+		{
+			name: "Backend status",
+			call: func() error {
+				_, err := chirps.DeleteChirp(withToken(validToken), &chirpypb.DeleteChirpRequest{Id: uuid.NewString()})
+				return err
+			},
+			wantCode:    codes.PermissionDenied,
+			wantMessage: "You are not the owner of this chirp",
+		},
+		{
+			name: "Backend failure is hidden",
+			call: func() error {
+				_, err := auth.RevokeToken(context.Background(), &chirpypb.RevokeTokenRequest{RefreshToken: "token"})
+				return err
+			},
+			wantCode:    codes.Internal,
+			wantMessage: "Internal error",
+		},
+		{
+			name: "Wrong password",
+			call: func() error {
+				_, err := auth.Login(context.Background(), &chirpypb.LoginRequest{Email: "user@example.com", Password: "wrong"})
+				return err
+			},
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "Incorrect email or password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(tt.call())
+			if st.Code() != tt.wantCode || st.Message() != tt.wantMessage {
+				t.Errorf("error = %v %q, want %v %q", st.Code(), st.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestStreamChirps(t *testing.T) {
+	backend := newFakeBackend()
+	chirps := chirpypb.NewChirpsServiceClient(dial(t, backend))
+
+	author := uuid.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := chirps.StreamChirps(ctx, &chirpypb.StreamChirpsRequest{AuthorId: author.String()})
+	if err != nil {
+		t.Fatalf("StreamChirps() error = %v", err)
+	}
+
+	select {
+	case <-backend.watching:
+	case <-ctx.Done():
+		t.Fatal("stream never subscribed")
+	}
+	want := Chirp{ID: uuid.New(), UserID: author, Body: "Live"}
+	backend.live <- Chirp{ID: uuid.New(), UserID: uuid.New(), Body: "Someone else"}
+	backend.live <- want
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if resp.GetChirp().GetId() != want.ID.String() {
+		t.Errorf("Recv() = %s, want %s", resp.GetChirp().GetId(), want.ID)
+	}
+}
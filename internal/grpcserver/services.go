@@ -0,0 +1,213 @@
+package grpcserver
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/RodolfoCamposGlz/internal/chirpypb"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type usersService struct {
+	chirpypb.UnimplementedUsersServiceServer
+	backend Backend
+}
+
+func (s *usersService) CreateUser(ctx context.Context, req *chirpypb.CreateUserRequest) (*chirpypb.CreateUserResponse, error) {
+	user, err := s.backend.CreateUser(ctx, req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.CreateUserResponse{User: userToProto(user)}, nil
+}
+
+func (s *usersService) GetCurrentUser(ctx context.Context, req *chirpypb.GetCurrentUserRequest) (*chirpypb.GetCurrentUserResponse, error) {
+	userID, _ := UserID(ctx)
+	user, err := s.backend.GetUser(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.GetCurrentUserResponse{User: userToProto(user)}, nil
+}
+
+type authService struct {
+	chirpypb.UnimplementedAuthServiceServer
+	backend Backend
+}
+
+func (s *authService) Login(ctx context.Context, req *chirpypb.LoginRequest) (*chirpypb.LoginResponse, error) {
+	session, err := s.backend.Login(ctx, req.GetEmail(), req.GetPassword(), peerIP(ctx))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if session.MFAToken != "" {
+		return &chirpypb.LoginResponse{MfaRequired: true, MfaToken: session.MFAToken}, nil
+	}
+	return &chirpypb.LoginResponse{
+		User:         userToProto(session.User),
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+	}, nil
+}
+
+func (s *authService) LoginMFA(ctx context.Context, req *chirpypb.LoginMFARequest) (*chirpypb.LoginMFAResponse, error) {
+	session, err := s.backend.LoginMFA(ctx, req.GetMfaToken(), req.GetCode(), peerIP(ctx))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.LoginMFAResponse{
+		User:         userToProto(session.User),
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+	}, nil
+}
+
+func (s *authService) RefreshToken(ctx context.Context, req *chirpypb.RefreshTokenRequest) (*chirpypb.RefreshTokenResponse, error) {
+	accessToken, err := s.backend.RefreshToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.RefreshTokenResponse{AccessToken: accessToken}, nil
+}
+
+func (s *authService) RevokeToken(ctx context.Context, req *chirpypb.RevokeTokenRequest) (*chirpypb.RevokeTokenResponse, error) {
+	err := s.backend.RevokeToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.RevokeTokenResponse{}, nil
+}
+
+type chirpsService struct {
+	chirpypb.UnimplementedChirpsServiceServer
+	backend Backend
+}
+
+func (s *chirpsService) CreateChirp(ctx context.Context, req *chirpypb.CreateChirpRequest) (*chirpypb.CreateChirpResponse, error) {
+	userID, _ := UserID(ctx)
+	chirp, err := s.backend.CreateChirp(ctx, userID, req.GetBody())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.CreateChirpResponse{Chirp: chirpToProto(chirp)}, nil
+}
+
+func (s *chirpsService) GetChirp(ctx context.Context, req *chirpypb.GetChirpRequest) (*chirpypb.GetChirpResponse, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid chirp ID")
+	}
+	chirp, err := s.backend.GetChirp(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.GetChirpResponse{Chirp: chirpToProto(chirp)}, nil
+}
+
+func (s *chirpsService) ListChirps(ctx context.Context, req *chirpypb.ListChirpsRequest) (*chirpypb.ListChirpsResponse, error) {
+	authorID, err := parseAuthorID(req.GetAuthorId())
+	if err != nil {
+		return nil, err
+	}
+	chirps, err := s.backend.ListChirps(ctx, authorID, req.GetSort() == chirpypb.SortOrder_SORT_ORDER_DESC)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &chirpypb.ListChirpsResponse{Chirps: make([]*chirpypb.Chirp, len(chirps))}
+	for i, chirp := range chirps {
+		resp.Chirps[i] = chirpToProto(chirp)
+	}
+	return resp, nil
+}
+
+func (s *chirpsService) DeleteChirp(ctx context.Context, req *chirpypb.DeleteChirpRequest) (*chirpypb.DeleteChirpResponse, error) {
+	userID, _ := UserID(ctx)
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid chirp ID")
+	}
+	err = s.backend.DeleteChirp(ctx, userID, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &chirpypb.DeleteChirpResponse{}, nil
+}
+
+func (s *chirpsService) StreamChirps(req *chirpypb.StreamChirpsRequest, stream grpc.ServerStreamingServer[chirpypb.StreamChirpsResponse]) error {
+	authorID, err := parseAuthorID(req.GetAuthorId())
+	if err != nil {
+		return err
+	}
+	err = s.backend.WatchChirps(stream.Context(), authorID, func(chirp Chirp) error {
+		return stream.Send(&chirpypb.StreamChirpsResponse{Chirp: chirpToProto(chirp)})
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+func parseAuthorID(raw string) (uuid.NullUUID, error) {
+	if raw == "" {
+		return uuid.NullUUID{}, nil
+	}
+	authorID, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.NullUUID{}, status.Error(codes.InvalidArgument, "Invalid author ID")
+	}
+	return uuid.NullUUID{UUID: authorID, Valid: true}, nil
+}
+
+// toStatus returns err as a status error, hiding the details of errors the
+// backend didn't mean the caller to see
+func toStatus(err error) error {
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded:
+		return err
+	}
+	log.Printf("Error: %v\n", err)
+	return status.Error(codes.Internal, "Internal error")
+}
+
+// peerIP returns the caller's address without its port
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func userToProto(user User) *chirpypb.User {
+	return &chirpypb.User{
+		Id:              user.ID.String(),
+		Email:           user.Email,
+		CreateTime:      timestamppb.New(user.CreatedAt),
+		UpdateTime:      timestamppb.New(user.UpdatedAt),
+		IsChirpyRed:     user.IsChirpyRed,
+		IsEmailVerified: user.IsEmailVerified,
+	}
+}
+
+func chirpToProto(chirp Chirp) *chirpypb.Chirp {
+	return &chirpypb.Chirp{
+		Id:         chirp.ID.String(),
+		UserId:     chirp.UserID.String(),
+		Body:       chirp.Body,
+		CreateTime: timestamppb.New(chirp.CreatedAt),
+		UpdateTime: timestamppb.New(chirp.UpdatedAt),
+	}
+}
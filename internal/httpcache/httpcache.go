@@ -0,0 +1,121 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag returns a strong entity tag for a representation
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// SetValidators sets the ETag and Last-Modified headers. A zero
+// lastModified is left out.
+func SetValidators(w http.ResponseWriter, etag string, lastModified time.Time) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified reports whether the client's cached copy is current, in which
+// case the response should be a 304. As in RFC 9110, If-Modified-Since is
+// only used when there's no If-None-Match.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && matchesETag(inm, etag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// Last-Modified only has second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// matchesETag compares with the weak comparison If-None-Match calls for
+func matchesETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Versions builds validators from the IDs and update times of the records
+// a representation is made of, so a conditional request can be answered
+// before rendering it. The representation must only depend on those
+// records, in the order they're added.
+type Versions struct {
+	hash         hash.Hash
+	lastModified time.Time
+}
+
+// NewVersions returns Versions with no records
+func NewVersions() *Versions {
+	return &Versions{hash: sha256.New()}
+}
+
+// Add records a record's ID and when it was last updated
+func (v *Versions) Add(id string, updatedAt time.Time) {
+	fmt.Fprintf(v.hash, "%s\x00%d\n", id, updatedAt.UnixNano())
+	if updatedAt.After(v.lastModified) {
+		v.lastModified = updatedAt
+	}
+}
+
+// ETag returns a strong entity tag that changes whenever a record is added,
+// removed, reordered or updated
+func (v *Versions) ETag() string {
+	sum := v.hash.Sum(nil)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// LastModified returns the latest update time, zero when nothing was added
+func (v *Versions) LastModified() time.Time {
+	return v.lastModified
+}
+
+// CheckNotModified sets the validators and, when the client's copy is
+// current, answers with a 304. It reports whether it did, otherwise the
+// caller sends the full response.
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	SetValidators(w, etag, lastModified)
+	if NotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// Write sends body with validators, or a 304 without it when the client's
+// copy is current
+func Write(w http.ResponseWriter, r *http.Request, contentType string, body []byte, lastModified time.Time) {
+	if CheckNotModified(w, r, ETag(body), lastModified) {
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
@@ -0,0 +1,209 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	etag := ETag([]byte("hello"))
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 500, time.UTC)
+
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		want   bool
+	}{
+		{
+			name: "No validators",
+			want: false,
+		},
+		{
+			name:   "Matching ETag",
+			header: map[string]string{"If-None-Match": etag},
+			want:   true,
+		},
+		{
+			name:   "Matching weak ETag in a list",
+			header: map[string]string{"If-None-Match": `"other", W/` + etag},
+			want:   true,
+		},
+		{
+			name:   "Wildcard",
+			header: map[string]string{"If-None-Match": "*"},
+			want:   true,
+		},
+		{
+			name:   "Different ETag",
+			header: map[string]string{"If-None-Match": `"other"`},
+			want:   false,
+		},
+		{
+			name:   "Not modified since",
+			header: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)},
+			want:   true,
+		},
+		{
+			name:   "Modified since",
+			header: map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)},
+			want:   false,
+		},
+		{
+			name: "If-None-Match wins over If-Modified-Since",
+			header: map[string]string{
+				"If-None-Match":     `"other"`,
+				"If-Modified-Since": modified.Format(http.TimeFormat),
+			},
+			want: false,
+		},
+		{
+			name:   "Invalid date",
+			header: map[string]string{"If-Modified-Since": "yesterday"},
+			want:   false,
+		},
+		{
+			name:   "Unsafe method",
+			method: http.MethodPost,
+			header: map[string]string{"If-None-Match": etag},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/", nil)
+			for key, value := range tt.header {
+				r.Header.Set(key, value)
+			}
+			got := NotModified(r, etag, modified)
+			if got != tt.want {
+				t.Errorf("NotModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	body := []byte("<feed/>")
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	Write(w, httptest.NewRequest(http.MethodGet, "/", nil), "application/atom+xml", body, modified)
+	if w.Code != http.StatusOK || w.Body.String() != string(body) {
+		t.Fatalf("got %d %q, want 200 with the body", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag != ETag(body) {
+		t.Errorf("ETag = %q, want %q", etag, ETag(body))
+	}
+	if got := w.Header().Get("Last-Modified"); got != "Thu, 02 Jan 2025 03:04:05 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	Write(w, r, "application/atom+xml", body, modified)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("got %d %q, want an empty 304", w.Code, w.Body.String())
+	}
+}
+
+func TestVersions(t *testing.T) {
+	older := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	etag := func(add func(v *Versions)) string {
+		v := NewVersions()
+		add(v)
+		return v.ETag()
+	}
+	base := etag(func(v *Versions) {
+		v.Add("a", older)
+		v.Add("b", newer)
+	})
+
+	tests := []struct {
+		name string
+		add  func(v *Versions)
+		same bool
+	}{
+		{
+			name: "Same records",
+			add: func(v *Versions) {
+				v.Add("a", older)
+				v.Add("b", newer)
+			},
+			same: true,
+		},
+		{
+			name: "Updated",
+			add: func(v *Versions) {
+				v.Add("a", newer)
+				v.Add("b", newer)
+			},
+		},
+		{
+			name: "Reordered",
+			add: func(v *Versions) {
+				v.Add("b", newer)
+				v.Add("a", older)
+			},
+		},
+		{
+			name: "Removed",
+			add: func(v *Versions) {
+				v.Add("a", older)
+			},
+		},
+		{
+			name: "Empty",
+			add:  func(v *Versions) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := etag(tt.add)
+			if (got == base) != tt.same {
+				t.Errorf("ETag() = %q, base %q, want same = %v", got, base, tt.same)
+			}
+		})
+	}
+
+	v := NewVersions()
+	if !v.LastModified().IsZero() {
+		t.Errorf("LastModified() = %v with no records, want zero", v.LastModified())
+	}
+	v.Add("b", newer)
+	v.Add("a", older)
+	if !v.LastModified().Equal(newer) {
+		t.Errorf("LastModified() = %v, want %v", v.LastModified(), newer)
+	}
+}
+
+func TestCheckNotModified(t *testing.T) {
+	etag := `"abc"`
+	modified := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	if CheckNotModified(w, httptest.NewRequest(http.MethodGet, "/", nil), etag, modified) {
+		t.Fatal("CheckNotModified() = true without validators in the request")
+	}
+	if w.Header().Get("ETag") != etag || w.Header().Get("Last-Modified") == "" {
+		t.Errorf("validators not set: %v", w.Header())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	w = httptest.NewRecorder()
+	if !CheckNotModified(w, r, etag, modified) || w.Code != http.StatusNotModified {
+		t.Errorf("CheckNotModified() didn't answer with a 304, got %d", w.Code)
+	}
+}
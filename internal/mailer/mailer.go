@@ -0,0 +1,89 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a Mailer
+type Config struct {
+	// Kind is one of "smtp", "file" or "log". Defaults to "log".
+	Kind         string
+	From         string
+	Dir          string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// New builds the Mailer described by cfg
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Kind {
+	case "", "log":
+		return &LogMailer{From: cfg.From}, nil
+	case "file":
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("file mailer requires a directory")
+		}
+		return &FileMailer{Dir: cfg.Dir, From: cfg.From}, nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("smtp mailer requires a host")
+		}
+		port := cfg.SMTPPort
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     port,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer kind %q", cfg.Kind)
+	}
+}
+
+// ErrInvalidHeader is returned for an address or subject with a line
+// break, which could otherwise add headers to the email
+var ErrInvalidHeader = errors.New("email headers can't contain line breaks")
+
+// format renders msg as an RFC 5322 message. Subjects that aren't ASCII
+// are encoded as RFC 2047 words.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name:    "Default to log mailer",
+			cfg:     Config{},
+			wantErr: false,
+		},
+		{
+			name:    "File mailer",
+			cfg:     Config{Kind: "file", Dir: t.TempDir()},
+			wantErr: false,
+		},
+		{
+			name:    "File mailer without directory",
+			cfg:     Config{Kind: "file"},
+			wantErr: true,
+		},
+		{
+			name:    "SMTP mailer without host",
+			cfg:     Config{Kind: "smtp"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown kind",
+			cfg:     Config{Kind: "pigeon"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileMailerSend(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "chirpy@example.com"}
+	err := m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "Welcome to Chirpy",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 email file, got %d", len(entries))
+	}
+	contents, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"From: chirpy@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Hello\r\n",
+		"\r\n\r\nWelcome to Chirpy",
+	} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("email is missing %q:\n%s", want, contents)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Plain",
+			msg:  Message{To: "user@example.com", Subject: "Hello", Body: "Hi"},
+			want: "Subject: Hello\r\n",
+		},
+		{
+			name: "Encoded subject",
+			msg:  Message{To: "user@example.com", Subject: "¡Hola!", Body: "Hi"},
+			want: "Subject: =?utf-8?q?=C2=A1Hola!?=\r\n",
+		},
+		{
+			name:    "Line break in recipient",
+			msg:     Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hello"},
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name:    "Line break in subject",
+			msg:     Message{To: "user@example.com", Subject: "Hello\nBcc: victim@example.com"},
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name:    "Carriage return in subject",
+			msg:     Message{To: "user@example.com", Subject: "Hello\rX-Injected: yes"},
+			wantErr: ErrInvalidHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := format("chirpy@example.com", tt.msg, time.Now())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("format() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !strings.Contains(string(got), tt.want) {
+				t.Errorf("format() is missing %q:\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package openapi
+
+import (
+	_ "embed"
+)
+
+// Spec is the OpenAPI 3.1 document describing the API. Every route
+// registered in main must have an operation in it, which the package's
+// tests check.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// DocsHTML renders the document at specURL with Redoc
+func DocsHTML(specURL string) []byte {
+	return []byte(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>Chirpy API</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+  </head>
+  <body>
+    <redoc spec-url="` + specURL + `"></redoc>
+    <script src="https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"></script>
+  </body>
+</html>
+`)
+}
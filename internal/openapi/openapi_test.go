@@ -0,0 +1,174 @@
+package openapi
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type document struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type operation struct {
+	Parameters []parameter `json:"parameters"`
+}
+
+type parameter struct {
+	Name string `json:"name"`
+	In   string `json:"in"`
+}
+
+// registeredRoutes returns "method path" for every route main.go registers
+// on its mux, in the spec's terms
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "../../main.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parsing main.go: %v", err)
+	}
+
+	routes := []string{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "mux" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			t.Errorf("found a route pattern that isn't a string literal")
+			return true
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		method, path := OperationKey(pattern)
+		routes = append(routes, method+" "+path)
+		return true
+	})
+	if len(routes) == 0 {
+		t.Fatal("found no routes in main.go")
+	}
+	return routes
+}
+
+func loadDocument(t *testing.T) document {
+	t.Helper()
+	doc := document{}
+	err := json.Unmarshal(Spec, &doc)
+	if err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	doc := loadDocument(t)
+	registered := map[string]bool{}
+	for _, route := range registeredRoutes(t) {
+		registered[route] = true
+		method, path, _ := strings.Cut(route, " ")
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("route %s %s is registered in main.go but missing from openapi.json", strings.ToUpper(method), path)
+		}
+	}
+
+	documented := []string{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(documented)
+	for _, route := range documented {
+		if !registered[route] {
+			t.Errorf("operation %s is in openapi.json but no route is registered for it", route)
+		}
+	}
+}
+
+func TestSpecPathParameters(t *testing.T) {
+	doc := loadDocument(t)
+	placeholder := regexp.MustCompile(`\{(\w+)\}`)
+	for path, item := range doc.Paths {
+		shared := operation{}
+		if raw, ok := item["parameters"]; ok {
+			json.Unmarshal([]byte(`{"parameters":`+string(raw)+`}`), &shared)
+		}
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			op := operation{}
+			err := json.Unmarshal(raw, &op)
+			if err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+			declared := map[string]bool{}
+			for _, param := range append(shared.Parameters, op.Parameters...) {
+				if param.In == "path" {
+					declared[param.Name] = true
+				}
+			}
+			for _, match := range placeholder.FindAllStringSubmatch(path, -1) {
+				if !declared[match[1]] {
+					t.Errorf("%s %s doesn't declare the %s path parameter", method, path, match[1])
+				}
+			}
+		}
+	}
+}
+
+func TestSpecRefsResolve(t *testing.T) {
+	root := map[string]any{}
+	err := json.Unmarshal(Spec, &root)
+	if err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				if resolve(root, ref) == nil {
+					t.Errorf("$ref %s doesn't resolve", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+}
+
+func resolve(root map[string]any, ref string) any {
+	var node any = root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = object[part]
+	}
+	return node
+}
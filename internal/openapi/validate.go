@@ -0,0 +1,526 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// MaxBodyBytes is the largest request body that is validated, larger ones
+// are rejected
+const MaxBodyBytes = 1 << 20
+
+// FieldError is one problem with a request. In is "body", "query" or
+// "path", Field is the parameter name or the path to a value in the body
+// such as events[0], empty for the body as a whole.
+type FieldError struct {
+	In      string `json:"in"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validator checks requests against the operations in an OpenAPI document.
+// It understands the parts of JSON Schema the document uses.
+type Validator struct {
+	schemas    map[string]*Schema
+	operations map[string]*operationSpec
+}
+
+type operationSpec struct {
+	path         string
+	params       []parameterSpec
+	body         *Schema
+	bodyRequired bool
+}
+
+type parameterSpec struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaType         `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []json.RawMessage  `json:"enum"`
+	Const                json.RawMessage    `json:"const"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	OneOf                []*Schema          `json:"oneOf"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+}
+
+// schemaType is a type keyword, which can be a single type or a list
+type schemaType []string
+
+func (t *schemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*t = schemaType{single}
+		return nil
+	}
+	var list []string
+	err := json.Unmarshal(data, &list)
+	*t = list
+	return err
+}
+
+// additional is an additionalProperties keyword, either a boolean or a
+// schema for the extra properties
+type additional struct {
+	allowed bool
+	schema  *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if json.Unmarshal(data, &a.allowed) == nil {
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(data, &a.schema)
+}
+
+// OperationKey turns a ServeMux pattern into the method and path of the
+// operation that documents it. Patterns without a method are documented as
+// GET, subtree patterns such as /app/ as /app/{path}.
+func OperationKey(pattern string) (method, path string) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = http.MethodGet, pattern
+	}
+	if path != "/" && strings.HasSuffix(path, "/") {
+		path += "{path}"
+	}
+	return strings.ToLower(method), path
+}
+
+// NewValidator compiles the operations in an OpenAPI document
+func NewValidator(spec []byte) (*Validator, error) {
+	type operationJSON struct {
+		Parameters  []parameterSpec `json:"parameters"`
+		RequestBody *struct {
+			Required bool `json:"required"`
+			Content  map[string]struct {
+				Schema *Schema `json:"schema"`
+			} `json:"content"`
+		} `json:"requestBody"`
+	}
+	doc := struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*Schema `json:"schemas"`
+		} `json:"components"`
+	}{}
+	err := json.Unmarshal(spec, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Validator{
+		schemas:    doc.Components.Schemas,
+		operations: map[string]*operationSpec{},
+	}
+	for path, item := range doc.Paths {
+		shared := []parameterSpec{}
+		if raw, ok := item["parameters"]; ok {
+			err := json.Unmarshal(raw, &shared)
+			if err != nil {
+				return nil, fmt.Errorf("%s parameters: %w", path, err)
+			}
+		}
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			op := operationJSON{}
+			err := json.Unmarshal(raw, &op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			compiled := &operationSpec{
+				path:   path,
+				params: append(slices.Clone(shared), op.Parameters...),
+			}
+			if op.RequestBody != nil {
+				if content, ok := op.RequestBody.Content["application/json"]; ok {
+					compiled.body = content.Schema
+					compiled.bodyRequired = op.RequestBody.Required
+				}
+			}
+			v.operations[method+" "+path] = compiled
+		}
+	}
+	return v, nil
+}
+
+// ValidateRequest checks r against the operation documenting pattern, the
+// ServeMux pattern r is routed to. Requests for routes the document doesn't
+// describe pass. The body is read and replaced so handlers can still
+// decode it.
+func (v *Validator) ValidateRequest(r *http.Request, pattern string) []FieldError {
+	if pattern == "" {
+		return nil
+	}
+	method, path := OperationKey(pattern)
+	op, ok := v.operations[method+" "+path]
+	if !ok {
+		return nil
+	}
+
+	c := &checker{schemas: v.schemas}
+	pathValues := matchPath(op.path, r.URL)
+	query := r.URL.Query()
+	for _, param := range op.params {
+		c.in = param.In
+		switch param.In {
+		case "path":
+			c.checkParameter(param, pathValues[param.Name])
+		case "query":
+			if !query.Has(param.Name) {
+				if param.Required {
+					c.fail(param.Name, "is required")
+				}
+				continue
+			}
+			c.checkParameter(param, query.Get(param.Name))
+		}
+	}
+
+	if op.body != nil {
+		c.in = "body"
+		c.checkBody(r, op.body, op.bodyRequired)
+	}
+	return c.errs
+}
+
+// matchPath returns the values of the placeholders in a path template. A
+// placeholder at the end takes the rest of the path, like a subtree
+// pattern.
+func matchPath(template string, u *url.URL) map[string]string {
+	values := map[string]string{}
+	names := strings.Split(strings.TrimPrefix(template, "/"), "/")
+	segments := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	for i, name := range names {
+		if i >= len(segments) {
+			break
+		}
+		if !strings.HasPrefix(name, "{") || !strings.HasSuffix(name, "}") {
+			continue
+		}
+		segment := segments[i]
+		if i == len(names)-1 {
+			segment = strings.Join(segments[i:], "/")
+		}
+		unescaped, err := url.PathUnescape(segment)
+		if err == nil {
+			values[strings.Trim(name, "{}")] = unescaped
+		}
+	}
+	return values
+}
+
+type checker struct {
+	schemas map[string]*Schema
+	in      string
+	errs    []FieldError
+}
+
+func (c *checker) fail(field, format string, args ...any) {
+	c.errs = append(c.errs, FieldError{In: c.in, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkParameter converts a path or query value to the schema's type
+// before checking it
+func (c *checker) checkParameter(param parameterSpec, raw string) {
+	schema := c.resolve(param.Schema)
+	if schema == nil {
+		return
+	}
+	var value any = raw
+	switch {
+	case slices.Contains(schema.Type, "integer"):
+		_, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.fail(param.Name, "must be an integer")
+			return
+		}
+		value = json.Number(raw)
+	case slices.Contains(schema.Type, "number"):
+		_, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.fail(param.Name, "must be a number")
+			return
+		}
+		value = json.Number(raw)
+	case slices.Contains(schema.Type, "boolean"):
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.fail(param.Name, "must be true or false")
+			return
+		}
+		value = parsed
+	}
+	c.check(schema, value, param.Name)
+}
+
+func (c *checker) checkBody(r *http.Request, schema *Schema, required bool) {
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		c.fail("", "couldn't be read")
+		return
+	}
+	if len(body) > MaxBodyBytes {
+		c.fail("", "must be at most %d bytes", MaxBodyBytes)
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if required {
+			c.fail("", "is required")
+		}
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	err = decoder.Decode(&value)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON value")
+	}
+	if err != nil {
+		c.fail("", "must be valid JSON")
+		return
+	}
+	c.check(schema, value, "")
+}
+
+func (c *checker) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = c.schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// check validates a value decoded with UseNumber
+func (c *checker) check(schema *Schema, value any, field string) {
+	schema = c.resolve(schema)
+	if schema == nil {
+		return
+	}
+
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, option := range schema.OneOf {
+			sub := &checker{schemas: c.schemas, in: c.in}
+			sub.check(option, value, field)
+			if len(sub.errs) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			c.fail(field, "must match exactly one of the allowed shapes")
+			return
+		}
+	}
+
+	if len(schema.Type) > 0 && !slices.ContainsFunc(schema.Type, func(t string) bool { return hasType(value, t) }) {
+		c.fail(field, "must be %s", describeTypes(schema.Type))
+		return
+	}
+	if len(schema.Const) > 0 && !jsonEqual(schema.Const, value) {
+		c.fail(field, "must be %s", schema.Const)
+		return
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(option json.RawMessage) bool { return jsonEqual(option, value) }) {
+		options := []string{}
+		for _, option := range schema.Enum {
+			options = append(options, strings.Trim(string(option), `"`))
+		}
+		c.fail(field, "must be one of: %s", strings.Join(options, ", "))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		c.checkString(schema, value, field)
+	case json.Number:
+		number, _ := value.Float64()
+		if schema.Minimum != nil && number < *schema.Minimum {
+			c.fail(field, "must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			c.fail(field, "must be at most %v", *schema.Maximum)
+		}
+	case []any:
+		if schema.MinItems != nil && len(value) < *schema.MinItems {
+			c.fail(field, "must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+			c.fail(field, "must have at most %d items", *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range value {
+				c.check(schema.Items, item, fmt.Sprintf("%s[%d]", field, i))
+			}
+		}
+	case map[string]any:
+		c.checkObject(schema, value, field)
+	}
+}
+
+func (c *checker) checkString(schema *Schema, value, field string) {
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			c.fail(field, "must not be empty")
+		} else {
+			c.fail(field, "must be at least %d characters long", *schema.MinLength)
+		}
+		return
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		c.fail(field, "must be at most %d characters long", *schema.MaxLength)
+		return
+	}
+
+	switch schema.Format {
+	case "uuid":
+		_, err := uuid.Parse(value)
+		if err != nil {
+			c.fail(field, "must be a UUID")
+		}
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			c.fail(field, "must be an email address")
+		}
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.fail(field, "must be an RFC 3339 date-time")
+		}
+	case "uri":
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			c.fail(field, "must be an absolute URL")
+		}
+	}
+}
+
+func (c *checker) checkObject(schema *Schema, value map[string]any, field string) {
+	prefix := ""
+	if field != "" {
+		prefix = field + "."
+	}
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			c.fail(prefix+name, "is required")
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := schema.Properties[name]; ok {
+			c.check(property, value[name], prefix+name)
+			continue
+		}
+		if schema.AdditionalProperties == nil {
+			continue
+		}
+		if !schema.AdditionalProperties.allowed {
+			c.fail(prefix+name, "is not a known field")
+		} else if schema.AdditionalProperties.schema != nil {
+			c.check(schema.AdditionalProperties.schema, value[name], prefix+name)
+		}
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := strconv.ParseInt(number.String(), 10, 64)
+		return err == nil
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func describeTypes(types schemaType) string {
+	names := map[string]string{
+		"null":    "null",
+		"boolean": "a boolean",
+		"string":  "a string",
+		"number":  "a number",
+		"integer": "an integer",
+		"array":   "an array",
+		"object":  "an object",
+	}
+	described := []string{}
+	for _, t := range types {
+		described = append(described, names[t])
+	}
+	return strings.Join(described, " or ")
+}
+
+// jsonEqual compares a value from the document with one from a request
+func jsonEqual(want json.RawMessage, value any) bool {
+	got, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	compact := bytes.Buffer{}
+	err = json.Compact(&compact, want)
+	return err == nil && bytes.Equal(compact.Bytes(), got)
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	validator, err := NewValidator(Spec)
+	if err != nil {
+		t.Fatalf("NewValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		method  string
+		target  string
+		body    string
+		want    []FieldError
+	}{
+		{
+			name:    "Valid user",
+			pattern: "POST /api/users",
+			method:  http.MethodPost,
+			target:  "/api/users",
+			body:    `{"email": "user@example.com", "password": "hunter2"}`,
+		},
+		{
+			name:    "Empty email and password",
+			pattern: "POST /api/users",
+			method:  http.MethodPost,
+			target:  "/api/users",
+			body:    `{"email": "", "password": ""}`,
+			want: []FieldError{
+				{In: "body", Field: "email", Message: "must be an email address"},
+				{In: "body", Field: "password", Message: "must not be empty"},
+			},
+		},
+		{
+			name:    "Missing fields, wrong types and unknown fields",
+			pattern: "POST /api/users",
+			method:  http.MethodPost,
+			target:  "/api/users",
+			body:    `{"email": 42, "admin": true}`,
+			want: []FieldError{
+				{In: "body", Field: "password", Message: "is required"},
+				{In: "body", Field: "admin", Message: "is not a known field"},
+				{In: "body", Field: "email", Message: "must be a string"},
+			},
+		},
+		{
+			name:    "Invalid JSON",
+			pattern: "POST /api/users",
+			method:  http.MethodPost,
+			target:  "/api/users",
+			body:    `{"email": `,
+			want:    []FieldError{{In: "body", Message: "must be valid JSON"}},
+		},
+		{
+			name:    "Missing body",
+			pattern: "POST /api/chirps",
+			method:  http.MethodPost,
+			target:  "/api/chirps",
+			want:    []FieldError{{In: "body", Message: "is required"}},
+		},
+		{
+			name:    "Array items",
+			pattern: "POST /api/webhooks",
+			method:  http.MethodPost,
+			target:  "/api/webhooks",
+			body:    `{"url": "https://example.com/hook", "events": ["chirp.created", "chirp.liked"]}`,
+			want:    []FieldError{{In: "body", Field: "events[1]", Message: "must be one of: chirp.created, chirp.deleted"}},
+		},
+		{
+			name:    "Invalid path parameter",
+			pattern: "GET /api/chirps/{chirpID}",
+			method:  http.MethodGet,
+			target:  "/api/chirps/not-a-uuid",
+			want:    []FieldError{{In: "path", Field: "chirpID", Message: "must be a UUID"}},
+		},
+		{
+			name:    "Invalid query parameters",
+			pattern: "GET /api/chirps",
+			method:  http.MethodGet,
+			target:  "/api/chirps?sort=sideways&author_id=1",
+			want: []FieldError{
+				{In: "query", Field: "author_id", Message: "must be a UUID"},
+				{In: "query", Field: "sort", Message: "must be one of: asc, desc"},
+			},
+		},
+		{
+			name:    "Integer query parameter out of range",
+			pattern: "GET /api/webhooks/{webhookID}/deliveries",
+			method:  http.MethodGet,
+			target:  "/api/webhooks/6f1c1a4e-8d4b-4d5e-9a3f-2b7c9e0d1f23/deliveries?limit=1000",
+			want:    []FieldError{{In: "query", Field: "limit", Message: "must be at most 500"}},
+		},
+		{
+			name:    "Required query parameter",
+			pattern: "GET /api/verify-email",
+			method:  http.MethodGet,
+			target:  "/api/verify-email",
+			want:    []FieldError{{In: "query", Field: "token", Message: "is required"}},
+		},
+		{
+			name:    "Undocumented route",
+			pattern: "GET /nowhere",
+			method:  http.MethodGet,
+			target:  "/nowhere?sort=sideways",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			got := validator.ValidateRequest(r, tt.pattern)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateRequest() = %+v, want %+v", got, tt.want)
+			}
+
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != tt.body {
+				t.Errorf("body after validation = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestOperationKey(t *testing.T) {
+	tests := []struct {
+		pattern string
+		method  string
+		path    string
+	}{
+		{"POST /api/users", "post", "/api/users"},
+		{"GET /api/chirps/{chirpID}", "get", "/api/chirps/{chirpID}"},
+		{"/", "get", "/"},
+		{"/app/", "get", "/app/{path}"},
+	}
+	for _, tt := range tests {
+		method, path := OperationKey(tt.pattern)
+		if method != tt.method || path != tt.path {
+			t.Errorf("OperationKey(%q) = %q, %q, want %q, %q", tt.pattern, method, path, tt.method, tt.path)
+		}
+	}
+}
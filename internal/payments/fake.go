@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+	"github.com/google/uuid"
+)
+
+var ErrFakeSignatureRejected = errors.New("fake provider rejected the signature")
+
+type fakeEvent struct {
+	ID               string     `json:"id"`
+	Type             string     `json:"type"`
+	UserID           uuid.UUID  `json:"user_id"`
+	Plan             string     `json:"plan,omitempty"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end,omitempty"`
+}
+
+// Fake is a provider for tests and local development. Its payloads are
+// SubscriptionEvents as JSON, with the subscriptions.Event as the type, and
+// it accepts any signature unless RejectSignatures is set.
+type Fake struct {
+	RejectSignatures bool
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) VerifySignature(header http.Header, body []byte, now time.Time) error {
+	if f.RejectSignatures {
+		return ErrFakeSignatureRejected
+	}
+	return nil
+}
+
+func (f *Fake) ParseEvent(body []byte) (SubscriptionEvent, error) {
+	var req fakeEvent
+	err := json.Unmarshal(body, &req)
+	if err != nil {
+		return SubscriptionEvent{}, fmt.Errorf("error decoding event: %w", err)
+	}
+	if req.ID == "" {
+		return SubscriptionEvent{}, ErrMissingEventID
+	}
+	event := SubscriptionEvent{
+		ID:     req.ID,
+		Type:   req.Type,
+		Event:  subscriptions.Event(req.Type),
+		UserID: req.UserID,
+		Plan:   req.Plan,
+	}
+	if req.CurrentPeriodEnd != nil {
+		event.CurrentPeriodEnd = *req.CurrentPeriodEnd
+	}
+	return event, nil
+}
+
+// Payload returns the body of a webhook that ParseEvent reads back as event
+func (f *Fake) Payload(event SubscriptionEvent) []byte {
+	req := fakeEvent{
+		ID:     event.ID,
+		Type:   string(event.Event),
+		UserID: event.UserID,
+		Plan:   event.Plan,
+	}
+	if !event.CurrentPeriodEnd.IsZero() {
+		req.CurrentPeriodEnd = &event.CurrentPeriodEnd
+	}
+	body, _ := json.Marshal(req)
+	return body
+}
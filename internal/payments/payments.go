@@ -0,0 +1,43 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMissingEventID   = errors.New("event has no ID")
+	ErrUnsupportedEvent = errors.New("unsupported event type")
+)
+
+// SubscriptionEvent is a provider's webhook event in a provider independent
+// form
+type SubscriptionEvent struct {
+	// ID is the provider's event ID, unique per provider
+	ID string
+	// Type is the provider's own name for the event
+	Type   string
+	Event  subscriptions.Event
+	UserID uuid.UUID
+	// Plan is empty when the provider doesn't say
+	Plan string
+	// CurrentPeriodEnd is the zero time when the provider doesn't say
+	CurrentPeriodEnd time.Time
+}
+
+// Provider receives webhooks from a payment provider. Implementations must
+// be safe for concurrent use.
+type Provider interface {
+	// Name identifies the provider in URLs and in the webhook event log
+	Name() string
+	// VerifySignature checks that a webhook was sent by the provider
+	VerifySignature(header http.Header, body []byte, now time.Time) error
+	// ParseEvent reads a webhook body. Events the provider sends that don't
+	// change a subscription are returned with their ID and Type set, along
+	// with ErrUnsupportedEvent.
+	ParseEvent(body []byte) (SubscriptionEvent, error)
+}
@@ -0,0 +1,99 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+	"github.com/RodolfoCamposGlz/internal/webhook"
+	"github.com/google/uuid"
+)
+
+func TestPolkaVerifySignature(t *testing.T) {
+	polka := NewPolka([]byte("whsec_test"))
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+
+	header := http.Header{}
+	header.Set("X-Polka-Signature", webhook.Sign(polka.Secret, body, now))
+	if err := polka.VerifySignature(header, body, now); err != nil {
+		t.Errorf("VerifySignature() error = %v, want nil", err)
+	}
+
+	header.Set("Authorization", "ApiKey whsec_test")
+	header.Del("X-Polka-Signature")
+	if err := polka.VerifySignature(header, body, now); !errors.Is(err, webhook.ErrMissingSignature) {
+		t.Errorf("VerifySignature() error = %v, want %v", err, webhook.ErrMissingSignature)
+	}
+}
+
+func TestPolkaParseEvent(t *testing.T) {
+	userID := uuid.New()
+	periodEnd := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		body    string
+		want    SubscriptionEvent
+		wantErr error
+	}{
+		{
+			name: "Upgrade",
+			body: `{"id":"evt_1","event":"user.upgraded","data":{"user_id":"` + userID.String() + `"}}`,
+			want: SubscriptionEvent{ID: "evt_1", Type: "user.upgraded", Event: subscriptions.EventUpgraded, UserID: userID},
+		},
+		{
+			name: "Renewal with plan and period end",
+			body: `{"id":"evt_2","event":"user.renewed","data":{"user_id":"` + userID.String() + `","plan":"chirpy_red","current_period_end":"2025-02-01T00:00:00Z"}}`,
+			want: SubscriptionEvent{ID: "evt_2", Type: "user.renewed", Event: subscriptions.EventRenewed, UserID: userID, Plan: "chirpy_red", CurrentPeriodEnd: periodEnd},
+		},
+		{
+			name:    "Unsupported event",
+			body:    `{"id":"evt_3","event":"user.created","data":{}}`,
+			want:    SubscriptionEvent{ID: "evt_3", Type: "user.created"},
+			wantErr: ErrUnsupportedEvent,
+		},
+		{
+			name:    "Missing ID",
+			body:    `{"event":"user.upgraded","data":{"user_id":"` + userID.String() + `"}}`,
+			wantErr: ErrMissingEventID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPolka(nil).ParseEvent([]byte(tt.body))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeRoundTrip(t *testing.T) {
+	fake := &Fake{}
+	event := SubscriptionEvent{
+		ID:               "evt_1",
+		Type:             string(subscriptions.EventCanceled),
+		Event:            subscriptions.EventCanceled,
+		UserID:           uuid.New(),
+		CurrentPeriodEnd: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	got, err := fake.ParseEvent(fake.Payload(event))
+	if err != nil {
+		t.Fatalf("ParseEvent() error = %v", err)
+	}
+	if got != event {
+		t.Errorf("ParseEvent() = %+v, want %+v", got, event)
+	}
+
+	fake.RejectSignatures = true
+	if err := fake.VerifySignature(http.Header{}, nil, time.Now()); err == nil {
+		t.Error("VerifySignature() error = nil, want an error")
+	}
+}
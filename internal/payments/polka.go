@@ -0,0 +1,75 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/subscriptions"
+	"github.com/RodolfoCamposGlz/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// polkaEvents maps Polka event types to subscription events
+var polkaEvents = map[string]subscriptions.Event{
+	"user.upgraded":       subscriptions.EventUpgraded,
+	"user.renewed":        subscriptions.EventRenewed,
+	"user.canceled":       subscriptions.EventCanceled,
+	"user.downgraded":     subscriptions.EventDowngraded,
+	"user.payment_failed": subscriptions.EventPaymentFailed,
+}
+
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID           string     `json:"user_id"`
+		Plan             string     `json:"plan"`
+		CurrentPeriodEnd *time.Time `json:"current_period_end"`
+	} `json:"data"`
+}
+
+// Polka verifies webhooks signed with the X-Polka-Signature header, see
+// webhook.Sign
+type Polka struct {
+	Secret []byte
+}
+
+func NewPolka(secret []byte) *Polka {
+	return &Polka{Secret: secret}
+}
+
+func (p *Polka) Name() string {
+	return "polka"
+}
+
+func (p *Polka) VerifySignature(header http.Header, body []byte, now time.Time) error {
+	return webhook.Verify(header.Get("X-Polka-Signature"), body, p.Secret, webhook.DefaultTolerance, now)
+}
+
+func (p *Polka) ParseEvent(body []byte) (SubscriptionEvent, error) {
+	var req polkaEvent
+	err := json.Unmarshal(body, &req)
+	if err != nil {
+		return SubscriptionEvent{}, fmt.Errorf("error decoding event: %w", err)
+	}
+	if req.ID == "" {
+		return SubscriptionEvent{}, ErrMissingEventID
+	}
+
+	event := SubscriptionEvent{ID: req.ID, Type: req.Event, Plan: req.Data.Plan}
+	subscriptionEvent, ok := polkaEvents[req.Event]
+	if !ok {
+		return event, ErrUnsupportedEvent
+	}
+	event.Event = subscriptionEvent
+	event.UserID, err = uuid.Parse(req.Data.UserID)
+	if err != nil {
+		return event, fmt.Errorf("invalid user ID: %w", err)
+	}
+	if req.Data.CurrentPeriodEnd != nil {
+		event.CurrentPeriodEnd = *req.Data.CurrentPeriodEnd
+	}
+	return event, nil
+}
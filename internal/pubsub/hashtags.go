@@ -0,0 +1,37 @@
+package pubsub
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Hashtags returns the distinct hashtags in text, lowercased and without
+// the leading #. A hashtag is a # at the start of a word followed by
+// letters, digits or underscores.
+func Hashtags(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isTagRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTagRune(runes[end]) {
+			end++
+		}
+		if end > i+1 {
+			tag := strings.ToLower(string(runes[i+1 : end]))
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		i = end - 1
+	}
+	return tags
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an event. The fields after Data are for subscribers to filter
+// on.
+type Message struct {
+	// ID increases with every message, subscribers resume after the last ID
+	// they saw
+	ID       int64           `json:"id"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+	AuthorID uuid.UUID       `json:"author_id"`
+	ChirpID  uuid.UUID       `json:"chirp_id"`
+	Hashtags []string        `json:"hashtags,omitempty"`
+	// Recipient is set on messages meant for a single user
+	Recipient uuid.UUID `json:"recipient"`
+}
+
+// Publisher sends messages to every Hub that should see them
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// subscriberBuffer is how many messages a subscriber can fall behind before
+// it is dropped
+const subscriberBuffer = 64
+
+var (
+	idMu   sync.Mutex
+	lastID int64
+)
+
+// NextID returns a message ID. IDs are Unix nanoseconds, bumped when needed
+// so they strictly increase within a process. Across processes they are
+// ordered as well as the clocks are.
+func NextID() int64 {
+	idMu.Lock()
+	defer idMu.Unlock()
+	lastID = max(lastID+1, time.Now().UnixNano())
+	return lastID
+}
+
+// Hub fans messages out to subscribers in this process and keeps the most
+// recent ones so subscribers can resume after a reconnect
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	recent      []Message
+	next        int
+	full        bool
+}
+
+// NewHub returns a Hub that keeps the last history messages for replay
+func NewHub(history int) *Hub {
+	return &Hub{
+		subscribers: map[*Subscription]struct{}{},
+		recent:      make([]Message, history),
+	}
+}
+
+// Publish delivers msg to this hub's subscribers, it implements Publisher
+// for single instance deployments. msg gets an ID if it has none.
+func (h *Hub) Publish(ctx context.Context, msg Message) error {
+	if msg.ID == 0 {
+		msg.ID = NextID()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.recent) > 0 {
+		h.recent[h.next] = msg
+		h.next = (h.next + 1) % len(h.recent)
+		h.full = h.full || h.next == 0
+	}
+	for sub := range h.subscribers {
+		if !sub.filter(msg) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			// Too slow, it can reconnect and resume from its last ID
+			h.remove(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a subscription to messages that pass filter. If
+// lastEventID is not zero, retained messages after it are sent first.
+func (h *Hub) Subscribe(filter func(Message) bool, lastEventID int64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{hub: h, filter: filter}
+	replay := []Message{}
+	if lastEventID != 0 {
+		for _, msg := range h.retained() {
+			if msg.ID > lastEventID && filter(msg) {
+				replay = append(replay, msg)
+			}
+		}
+	}
+	sub.ch = make(chan Message, len(replay)+subscriberBuffer)
+	for _, msg := range replay {
+		sub.ch <- msg
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// retained returns the kept messages, oldest first
+func (h *Hub) retained() []Message {
+	if !h.full {
+		return h.recent[:h.next]
+	}
+	return append(append([]Message{}, h.recent[h.next:]...), h.recent[:h.next]...)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscription receives messages from a Hub
+type Subscription struct {
+	hub    *Hub
+	filter func(Message) bool
+	ch     chan Message
+}
+
+// C returns the channel messages arrive on. It is closed when the
+// subscription is closed or dropped for falling behind.
+func (s *Subscription) C() <-chan Message {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
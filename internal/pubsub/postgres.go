@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultChannel is the Postgres notification channel messages are sent on
+const DefaultChannel = "chirpy_events"
+
+// PostgresPublisher sends messages with NOTIFY so that every instance
+// running ListenPostgres on the same database receives them, including the
+// one that published
+type PostgresPublisher struct {
+	DB      *sql.DB
+	Channel string
+}
+
+func (p *PostgresPublisher) Publish(ctx context.Context, msg Message) error {
+	if msg.ID == 0 {
+		msg.ID = NextID()
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = p.DB.ExecContext(ctx, "SELECT pg_notify($1, $2)", p.Channel, string(payload))
+	return err
+}
+
+// ListenPostgres publishes messages sent by PostgresPublisher on channel to
+// hub until ctx is done
+func ListenPostgres(ctx context.Context, dbURL, channel string, hub *Hub) error {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Error listening for events: %v\n", err)
+		}
+	})
+	defer listener.Close()
+	err := listener.Listen(channel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// nil after a reconnect, messages sent while disconnected are lost
+			if notification == nil {
+				continue
+			}
+			msg := Message{}
+			err := json.Unmarshal([]byte(notification.Extra), &msg)
+			if err != nil {
+				log.Printf("Error decoding event: %v\n", err)
+				continue
+			}
+			hub.Publish(ctx, msg)
+		case <-time.After(time.Minute):
+			go listener.Ping()
+		}
+	}
+}
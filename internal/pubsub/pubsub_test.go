@@ -0,0 +1,117 @@
+package pubsub
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func all(Message) bool { return true }
+
+func receive(t *testing.T, sub *Subscription, n int) []int64 {
+	t.Helper()
+	ids := []int64{}
+	for range n {
+		select {
+		case msg := <-sub.C():
+			ids = append(ids, msg.ID)
+		default:
+			t.Fatalf("got %d messages, want %d", len(ids), n)
+		}
+	}
+	return ids
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub(10)
+	everything := hub.Subscribe(all, 0)
+	defer everything.Close()
+	created := hub.Subscribe(func(msg Message) bool { return msg.Type == "chirp.created" }, 0)
+	defer created.Close()
+
+	hub.Publish(context.Background(), Message{ID: 1, Type: "chirp.created"})
+	hub.Publish(context.Background(), Message{ID: 2, Type: "chirp.deleted"})
+
+	if got := receive(t, everything, 2); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("unfiltered subscriber got %v, want [1 2]", got)
+	}
+	if got := receive(t, created, 1); !slices.Equal(got, []int64{1}) {
+		t.Errorf("filtered subscriber got %v, want [1]", got)
+	}
+	if len(created.C()) != 0 {
+		t.Errorf("filtered subscriber got an extra message")
+	}
+}
+
+func TestHubReplay(t *testing.T) {
+	hub := NewHub(3)
+	for id := int64(1); id <= 5; id++ {
+		hub.Publish(context.Background(), Message{ID: id})
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID int64
+		want        []int64
+	}{
+		{name: "No Last-Event-ID", lastEventID: 0, want: []int64{}},
+		{name: "Resume", lastEventID: 3, want: []int64{4, 5}},
+		{name: "Older than the history", lastEventID: 1, want: []int64{3, 4, 5}},
+		{name: "Up to date", lastEventID: 5, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := hub.Subscribe(all, tt.lastEventID)
+			defer sub.Close()
+			if got := receive(t, sub, len(tt.want)); !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := NewHub(0)
+	sub := hub.Subscribe(all, 0)
+	for range subscriberBuffer + 1 {
+		hub.Publish(context.Background(), Message{})
+	}
+	for range sub.C() {
+	}
+	// Closing a dropped subscription is safe
+	sub.Close()
+}
+
+func TestNextID(t *testing.T) {
+	previous := NextID()
+	for range 1000 {
+		id := NextID()
+		if id <= previous {
+			t.Fatalf("NextID() = %d after %d", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "no tags here", want: []string{}},
+		{text: "#Go is fun", want: []string{"go"}},
+		{text: "learning #go and #golang, #GO again", want: []string{"go", "golang"}},
+		{text: "email me@example.com#not a tag", want: []string{}},
+		{text: "#snake_case #123 #", want: []string{"snake_case", "123"}},
+		{text: "¡#café!", want: []string{"café"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Hashtags(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Hashtags(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
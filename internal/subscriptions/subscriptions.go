@@ -0,0 +1,122 @@
+package subscriptions
+
+import (
+	"errors"
+	"time"
+)
+
+// PlanChirpyRed is the only paid plan
+const PlanChirpyRed = "chirpy_red"
+
+// DefaultPeriod is the billing period assumed when the payment provider
+// doesn't say when the current one ends
+const DefaultPeriod = 30 * 24 * time.Hour
+
+type Status string
+
+const (
+	// StatusActive subscriptions are paid up until CurrentPeriodEnd
+	StatusActive Status = "active"
+	// StatusPastDue subscriptions had a renewal payment fail. They keep
+	// their benefits until CurrentPeriodEnd while the provider retries.
+	StatusPastDue Status = "past_due"
+	// StatusCanceled subscriptions were downgraded and ended immediately
+	StatusCanceled Status = "canceled"
+	// StatusExpired subscriptions reached CurrentPeriodEnd without renewing
+	StatusExpired Status = "expired"
+)
+
+type Event string
+
+const (
+	EventUpgraded      Event = "upgraded"
+	EventRenewed       Event = "renewed"
+	EventCanceled      Event = "canceled"
+	EventDowngraded    Event = "downgraded"
+	EventPaymentFailed Event = "payment_failed"
+	EventExpired       Event = "expired"
+)
+
+var (
+	ErrNoSubscription   = errors.New("user has no subscription")
+	ErrInactive         = errors.New("subscription is not active")
+	ErrUnsupportedEvent = errors.New("unsupported subscription event")
+)
+
+// State is a user's subscription as stored
+type State struct {
+	Plan              string
+	Status            Status
+	CurrentPeriodEnd  time.Time
+	CancelAtPeriodEnd bool
+}
+
+// Active reports whether the subscription grants its plan's benefits at now
+func (s State) Active(now time.Time) bool {
+	return (s.Status == StatusActive || s.Status == StatusPastDue) && now.Before(s.CurrentPeriodEnd)
+}
+
+// Apply returns the state after event. current is nil for users who never
+// subscribed. periodEnd is when the provider says the new period ends, or
+// the zero time if it didn't say.
+func Apply(current *State, event Event, plan string, periodEnd, now time.Time) (State, error) {
+	if plan == "" {
+		plan = PlanChirpyRed
+	}
+
+	switch event {
+	case EventUpgraded:
+		next := State{Plan: plan, Status: StatusActive, CurrentPeriodEnd: periodEnd}
+		if next.CurrentPeriodEnd.IsZero() {
+			next.CurrentPeriodEnd = now.Add(DefaultPeriod)
+		}
+		// Upgrading again never shortens a period that was already paid for
+		if current != nil && current.Active(now) && current.CurrentPeriodEnd.After(next.CurrentPeriodEnd) {
+			next.CurrentPeriodEnd = current.CurrentPeriodEnd
+		}
+		return next, nil
+	}
+
+	if current == nil {
+		return State{}, ErrNoSubscription
+	}
+	next := *current
+
+	switch event {
+	case EventRenewed:
+		if current.Status == StatusCanceled {
+			return State{}, ErrInactive
+		}
+		if periodEnd.IsZero() {
+			periodEnd = latest(current.CurrentPeriodEnd, now).Add(DefaultPeriod)
+		}
+		next.Status = StatusActive
+		next.CurrentPeriodEnd = latest(current.CurrentPeriodEnd, periodEnd)
+		next.CancelAtPeriodEnd = false
+	case EventCanceled:
+		if !current.Active(now) {
+			return State{}, ErrInactive
+		}
+		next.CancelAtPeriodEnd = true
+	case EventDowngraded:
+		next.Status = StatusCanceled
+		if current.CurrentPeriodEnd.After(now) {
+			next.CurrentPeriodEnd = now
+		}
+	case EventPaymentFailed:
+		if !current.Active(now) {
+			return State{}, ErrInactive
+		}
+		next.Status = StatusPastDue
+	default:
+		return State{}, ErrUnsupportedEvent
+	}
+	return next, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
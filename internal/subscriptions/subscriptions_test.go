@@ -0,0 +1,138 @@
+package subscriptions
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Now()
+	periodEnd := now.Add(10 * 24 * time.Hour)
+	active := &State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: periodEnd}
+	lapsed := &State{Plan: PlanChirpyRed, Status: StatusExpired, CurrentPeriodEnd: now.Add(-time.Hour)}
+	canceled := &State{Plan: PlanChirpyRed, Status: StatusCanceled, CurrentPeriodEnd: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name      string
+		current   *State
+		event     Event
+		periodEnd time.Time
+		want      State
+		wantErr   error
+	}{
+		{
+			name:  "Upgrade without a period end",
+			event: EventUpgraded,
+			want:  State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: now.Add(DefaultPeriod)},
+		},
+		{
+			name:      "Upgrade with a period end",
+			event:     EventUpgraded,
+			periodEnd: periodEnd,
+			want:      State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: periodEnd},
+		},
+		{
+			name:      "Upgrade keeps a longer paid period",
+			current:   &State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: now.Add(60 * 24 * time.Hour)},
+			event:     EventUpgraded,
+			periodEnd: periodEnd,
+			want:      State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: now.Add(60 * 24 * time.Hour)},
+		},
+		{
+			name:    "Renew extends the current period",
+			current: active,
+			event:   EventRenewed,
+			want:    State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: periodEnd.Add(DefaultPeriod)},
+		},
+		{
+			name:    "Renew a lapsed subscription",
+			current: lapsed,
+			event:   EventRenewed,
+			want:    State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: now.Add(DefaultPeriod)},
+		},
+		{
+			name:      "Renew clears a past due status",
+			current:   &State{Plan: PlanChirpyRed, Status: StatusPastDue, CurrentPeriodEnd: now.Add(time.Hour)},
+			event:     EventRenewed,
+			periodEnd: periodEnd,
+			want:      State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: periodEnd},
+		},
+		{
+			name:    "Renew a canceled subscription",
+			current: canceled,
+			event:   EventRenewed,
+			wantErr: ErrInactive,
+		},
+		{
+			name:    "Renew without a subscription",
+			event:   EventRenewed,
+			wantErr: ErrNoSubscription,
+		},
+		{
+			name:    "Cancel keeps benefits until the period ends",
+			current: active,
+			event:   EventCanceled,
+			want:    State{Plan: PlanChirpyRed, Status: StatusActive, CurrentPeriodEnd: periodEnd, CancelAtPeriodEnd: true},
+		},
+		{
+			name:    "Cancel a lapsed subscription",
+			current: lapsed,
+			event:   EventCanceled,
+			wantErr: ErrInactive,
+		},
+		{
+			name:    "Downgrade ends the subscription now",
+			current: active,
+			event:   EventDowngraded,
+			want:    State{Plan: PlanChirpyRed, Status: StatusCanceled, CurrentPeriodEnd: now},
+		},
+		{
+			name:    "Payment failure",
+			current: active,
+			event:   EventPaymentFailed,
+			want:    State{Plan: PlanChirpyRed, Status: StatusPastDue, CurrentPeriodEnd: periodEnd},
+		},
+		{
+			name:    "Unknown event",
+			current: active,
+			event:   Event("paused"),
+			wantErr: ErrUnsupportedEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.current, tt.event, "", tt.periodEnd, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Apply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateActive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		state State
+		want  bool
+	}{
+		{name: "Active", state: State{Status: StatusActive, CurrentPeriodEnd: now.Add(time.Hour)}, want: true},
+		{name: "Past due in period", state: State{Status: StatusPastDue, CurrentPeriodEnd: now.Add(time.Hour)}, want: true},
+		{name: "Active but lapsed", state: State{Status: StatusActive, CurrentPeriodEnd: now.Add(-time.Hour)}, want: false},
+		{name: "Canceled", state: State{Status: StatusCanceled, CurrentPeriodEnd: now.Add(time.Hour)}, want: false},
+		{name: "Expired", state: State{Status: StatusExpired, CurrentPeriodEnd: now.Add(-time.Hour)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
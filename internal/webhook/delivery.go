@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Headers set on outbound deliveries
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+// Delivery is one event sent to one subscriber
+type Delivery struct {
+	ID        string
+	URL       string
+	Secret    []byte
+	EventType string
+	Payload   []byte
+}
+
+// Sender posts signed deliveries to subscriber URLs
+type Sender struct {
+	Client *http.Client
+}
+
+// NewSender returns a Sender that gives up on a request after timeout. It
+// refuses to connect to addresses that aren't public unless allowPrivate
+// is set, which is only meant for receivers running locally in dev.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = dialControl
+	}
+	return &Sender{Client: &http.Client{
+		Timeout: timeout,
+		// No proxy, the dialer has to see the subscriber's address
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect would resend the payload somewhere the user didn't
+		// choose, subscribers have to give their final URL
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send posts d.Payload to d.URL signed with d.Secret. It returns the
+// response status code, if there was a response, and an error unless the
+// status was 2xx.
+func (s *Sender) Send(ctx context.Context, d Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(SignatureHeader, Sign(d.Secret, d.Payload, now))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// RetryPolicy decides when a failed delivery is tried again. The wait
+// doubles after every attempt starting at BaseDelay and capped at MaxDelay.
+// A delivery that failed MaxAttempts times is dead.
+type RetryPolicy struct {
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy retries for roughly a day
+var DefaultRetryPolicy = RetryPolicy{
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    6 * time.Hour,
+	MaxAttempts: 10,
+}
+
+// NextAttempt returns how long to wait after attempts failed attempts, and
+// false if the delivery should not be tried again
+func (p RetryPolicy) NextAttempt(attempts int) (time.Duration, bool) {
+	if attempts >= p.MaxAttempts {
+		return 0, false
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay, true
+		}
+	}
+	return min(delay, p.MaxDelay), true
+}
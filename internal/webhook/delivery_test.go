@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSenderSend(t *testing.T) {
+	secret := []byte("whsec_test")
+	payload := []byte(`{"type":"chirp.created"}`)
+
+	var got *http.Request
+	var gotBody []byte
+	status := http.StatusNoContent
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	sender := NewSender(5*time.Second, true)
+	delivery := Delivery{ID: "dlv_1", URL: receiver.URL, Secret: secret, EventType: "chirp.created", Payload: payload}
+
+	code, err := sender.Send(context.Background(), delivery, time.Now())
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("Send() = %d, %v, want %d, nil", code, err, http.StatusNoContent)
+	}
+	if got.Header.Get(EventHeader) != "chirp.created" || got.Header.Get(DeliveryHeader) != "dlv_1" {
+		t.Errorf("Send() headers = %v", got.Header)
+	}
+	err = Verify(got.Header.Get(SignatureHeader), gotBody, secret, DefaultTolerance, time.Now())
+	if err != nil {
+		t.Errorf("receiver couldn't verify the signature: %v", err)
+	}
+
+	status = http.StatusInternalServerError
+	code, err = sender.Send(context.Background(), delivery, time.Now())
+	if err == nil || code != http.StatusInternalServerError {
+		t.Errorf("Send() = %d, %v, want %d and an error", code, err, http.StatusInternalServerError)
+	}
+}
+
+func TestSenderDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	_, err := NewSender(5*time.Second, true).Send(context.Background(), Delivery{URL: receiver.URL}, time.Now())
+	if err == nil || followed {
+		t.Errorf("Send() error = %v, followed = %v, want an error and no redirect", err, followed)
+	}
+}
+
+func TestSenderRefusesPrivateAddresses(t *testing.T) {
+	reached := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer receiver.Close()
+
+	_, err := NewSender(5*time.Second, false).Send(context.Background(), Delivery{URL: receiver.URL}, time.Now())
+	if !errors.Is(err, ErrForbiddenAddress) || reached {
+		t.Errorf("Send() error = %v, reached = %v, want ErrForbiddenAddress and no request", err, reached)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		got := IsPublicAddr(netip.MustParseAddr(tt.addr))
+		if got != tt.want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyNextAttempt(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, MaxAttempts: 6}
+
+	tests := []struct {
+		attempts  int
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{attempts: 1, wantDelay: time.Second, wantRetry: true},
+		{attempts: 2, wantDelay: 2 * time.Second, wantRetry: true},
+		{attempts: 4, wantDelay: 8 * time.Second, wantRetry: true},
+		{attempts: 5, wantDelay: 10 * time.Second, wantRetry: true},
+		{attempts: 6, wantDelay: 0, wantRetry: false},
+	}
+
+	for _, tt := range tests {
+		delay, retry := policy.NextAttempt(tt.attempts)
+		if delay != tt.wantDelay || retry != tt.wantRetry {
+			t.Errorf("NextAttempt(%d) = %v, %v, want %v, %v", tt.attempts, delay, retry, tt.wantDelay, tt.wantRetry)
+		}
+	}
+}
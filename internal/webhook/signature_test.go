@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	now := time.Now()
+	valid := Sign(secret, body, now)
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "Valid signature", header: valid, body: body, now: now, wantErr: nil},
+		{name: "Missing header", header: "", body: body, now: now, wantErr: ErrMissingSignature},
+		{name: "Tampered body", header: valid, body: []byte(`{"id":"evt_2"}`), now: now, wantErr: ErrInvalidSignature},
+		{name: "Wrong secret", header: Sign([]byte("other"), body, now), body: body, now: now, wantErr: ErrInvalidSignature},
+		{name: "Too old", header: valid, body: body, now: now.Add(10 * time.Minute), wantErr: ErrExpiredSignature},
+		{name: "From the future", header: valid, body: body, now: now.Add(-10 * time.Minute), wantErr: ErrExpiredSignature},
+		{name: "Malformed header", header: "sha256=abc", body: body, now: now, wantErr: ErrInvalidSignature},
+		{name: "Rotated secret", header: valid + ",v1=deadbeef", body: body, now: now, wantErr: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.header, tt.body, secret, DefaultTolerance, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
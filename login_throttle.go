@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/mailer"
+)
+
+// loginThrottle is a key failed login attempts are counted against
+type loginThrottle struct {
+	key     string
+	policy  auth.LockoutPolicy
+	account bool
+}
+
+// loginThrottles returns the per-account and per-IP throttles for a login
+// attempt. Accounts are keyed by the submitted email so unknown emails are
+// throttled exactly like real ones.
+func (cfg *apiConfig) loginThrottles(ip, email string) []loginThrottle {
+	return []loginThrottle{
+		{key: "account:" + strings.ToLower(email), policy: auth.AccountLockoutPolicy, account: true},
+		{key: "ip:" + ip, policy: auth.IPLockoutPolicy},
+	}
+}
+
+// clientIP returns the address of the caller, trusting X-Forwarded-For only
+// when the server runs behind a proxy that sets it
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.trustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// loginAttempt is an attempt counted against its throttles before the
+// credentials are checked. failures is each throttle's count including it.
+type loginAttempt struct {
+	ip        string
+	throttles []loginThrottle
+	failures  []int32
+}
+
+// beginLoginAttempt counts an attempt from ip against every throttle,
+// unless one of them makes the caller wait, in which case it returns how
+// long and nothing is counted. Each throttle's row stays locked from the
+// check until the attempt is counted, so parallel attempts see the ones
+// before them instead of all passing the check together. Call
+// recordLoginFailure or forgiveLoginAttempt once the credentials are
+// checked.
+func (cfg *apiConfig) beginLoginAttempt(ctx context.Context, ip string, throttles []loginThrottle) (*loginAttempt, time.Duration, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	var retryAfter time.Duration
+	now := time.Now()
+	for _, throttle := range throttles {
+		record, err := qtx.GetLoginFailuresForUpdate(ctx, throttle.key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		wait := throttle.policy.RetryAfter(int(record.Failures), record.LastFailureAt, record.LockedUntil.Time, now)
+		retryAfter = max(retryAfter, wait)
+	}
+	if retryAfter > 0 {
+		return nil, retryAfter, nil
+	}
+
+	attempt := &loginAttempt{ip: ip, throttles: throttles}
+	for _, throttle := range throttles {
+		record, err := qtx.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Key:         throttle.key,
+			ResetBefore: now.Add(-throttle.policy.Window),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		attempt.failures = append(attempt.failures, record.Failures)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, 0, err
+	}
+	return attempt, 0, nil
+}
+
+// recordLoginFailure locks the throttles a failed attempt took to their
+// threshold. The owner of a locked account, if it exists, is told by
+// email, along with the attempt's ip.
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, attempt *loginAttempt, user *database.User) {
+	for i, throttle := range attempt.throttles {
+		if !throttle.policy.ShouldLock(int(attempt.failures[i])) {
+			continue
+		}
+		lockedUntil := time.Now().Add(throttle.policy.LockoutDuration)
+		err := cfg.dbQueries.LockLoginKey(ctx, database.LockLoginKeyParams{
+			Key:         throttle.key,
+			LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+		})
+		if err != nil {
+			log.Printf("Error locking login: %v\n", err)
+			continue
+		}
+		if throttle.account && user != nil {
+			go cfg.sendLockoutNotification(user.Email, attempt.ip, lockedUntil)
+		}
+	}
+}
+
+// forgiveLoginAttempt uncounts an attempt whose credentials were correct
+func (cfg *apiConfig) forgiveLoginAttempt(ctx context.Context, attempt *loginAttempt) {
+	for _, throttle := range attempt.throttles {
+		err := cfg.dbQueries.ForgiveLoginFailure(ctx, throttle.key)
+		if err != nil {
+			log.Printf("Error forgiving login attempt: %v\n", err)
+		}
+	}
+}
+
+// clearLoginFailures forgets failures against the account after a
+// successful login. Per-IP failures are kept so an attacker can't reset
+// them by logging into an account of their own.
+func (cfg *apiConfig) clearLoginFailures(ctx context.Context, throttles []loginThrottle) {
+	for _, throttle := range throttles {
+		if !throttle.account {
+			continue
+		}
+		err := cfg.dbQueries.ClearLoginFailures(ctx, throttle.key)
+		if err != nil {
+			log.Printf("Error clearing login failures: %v\n", err)
+		}
+	}
+}
+
+func (cfg *apiConfig) sendLockoutNotification(email, ip string, lockedUntil time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Your Chirpy account was temporarily locked",
+		Body: fmt.Sprintf(
+			"There were too many failed attempts to log in to your Chirpy account, the latest from %s.\n\n"+
+				"Logging in is blocked until %s.\n\n"+
+				"If this wasn't you, consider resetting your password.\n",
+			ip, lockedUntil.UTC().Format(time.RFC1123),
+		),
+	})
+	if err != nil {
+		log.Printf("Error sending lockout notification: %v\n", err)
+	}
+}
+
+func errTooManyAttempts(retryAfter time.Duration) *apiError {
+	return &apiError{
+		status:     http.StatusTooManyRequests,
+		message:    "Too many failed login attempts, try again later",
+		retryAfter: retryAfter,
+	}
+}
@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"sync/atomic"
+	"time"
 
+	"github.com/RodolfoCamposGlz/internal/auth"
 	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/RodolfoCamposGlz/internal/entitlements"
+	"github.com/RodolfoCamposGlz/internal/gql"
+	"github.com/RodolfoCamposGlz/internal/mailer"
+	"github.com/RodolfoCamposGlz/internal/openapi"
+	"github.com/RodolfoCamposGlz/internal/payments"
+	"github.com/RodolfoCamposGlz/internal/pubsub"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -17,10 +30,27 @@ const filepathRoot = "."
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db *sql.DB
 	dbQueries *database.Queries
 	platform string
-	jwtSecret string
-	polkaKey string
+	keyring *auth.Keyring
+	paymentProviders map[string]payments.Provider
+	entitlements *entitlements.Service
+	hub *pubsub.Hub
+	events pubsub.Publisher
+	signalHub *pubsub.Hub
+	signals pubsub.Publisher
+	adminAPIKey string
+	baseURL string
+	mailer mailer.Mailer
+	unverifiedRestrictions map[string]bool
+	mfaEncryptionKey []byte
+	passwordHasher *auth.PasswordHasher
+	dummyPasswordHash string
+	trustProxyHeaders bool
+	graphql *gql.Server
+	accountDeletionGracePeriod time.Duration
+	accountDeletionChirps string
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -71,6 +101,81 @@ func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
 
 
 
+// loadPasswordHasher reads the password hashing settings, defaulting to
+// argon2id with auth.DefaultArgon2Params
+func loadPasswordHasher() (*auth.PasswordHasher, error) {
+	hasher := *auth.DefaultPasswordHasher
+	if algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm != "" {
+		hasher.Algorithm = algorithm
+	}
+	settings := []struct {
+		env  string
+		bits int
+		set  func(uint64)
+	}{
+		{"ARGON2_MEMORY_KIB", 32, func(v uint64) { hasher.Argon2.Memory = uint32(v) }},
+		{"ARGON2_ITERATIONS", 32, func(v uint64) { hasher.Argon2.Iterations = uint32(v) }},
+		{"ARGON2_PARALLELISM", 8, func(v uint64) { hasher.Argon2.Parallelism = uint8(v) }},
+		{"BCRYPT_COST", 8, func(v uint64) { hasher.BcryptCost = int(v) }},
+	}
+	for _, setting := range settings {
+		value := os.Getenv(setting.env)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, setting.bits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", setting.env, err)
+		}
+		setting.set(parsed)
+	}
+	err := hasher.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &hasher, nil
+}
+
+// loadPaymentProviders returns the providers that can send webhooks, keyed
+// by name. A provider is only enabled once its secret is set. The fake
+// provider, which accepts unsigned events, is only enabled in dev.
+func loadPaymentProviders(platform string) map[string]payments.Provider {
+	providers := []payments.Provider{}
+	if secret := os.Getenv("POLKA_WEBHOOK_SECRET"); secret != "" {
+		providers = append(providers, payments.NewPolka([]byte(secret)))
+	}
+	if platform == "dev" {
+		providers = append(providers, &payments.Fake{})
+	}
+
+	byName := map[string]payments.Provider{}
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return byName
+}
+
+// newEventBus returns a hub that keeps history messages for replay and the
+// publisher that feeds it. The postgres backend fans messages out to every
+// instance through channel.
+func newEventBus(backend string, db *sql.DB, dbURL, channel string, history int) (*pubsub.Hub, pubsub.Publisher, error) {
+	hub := pubsub.NewHub(history)
+	switch backend {
+	case "", "memory":
+		return hub, hub, nil
+	case "postgres":
+		go func() {
+			err := pubsub.ListenPostgres(context.Background(), dbURL, channel, hub)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+		return hub, &pubsub.PostgresPublisher{DB: db, Channel: channel}, nil
+	default:
+		return nil, nil, fmt.Errorf("EVENTS_BACKEND must be memory or postgres")
+	}
+}
+
 var handler http.Handler = http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))
 
 func main (){
@@ -78,6 +183,14 @@ func main (){
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		err := runAdmin(os.Args[2:], os.Stdout, os.Stderr)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
 	}
@@ -85,27 +198,120 @@ func main (){
 	dbURL := os.Getenv("DB_URL")
 	platform := os.Getenv("PLATFORM")
 	jwtSecret := os.Getenv("JWT_SECRET")
-	polkaKey := os.Getenv("POLKA_KEY")
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+	mail, err := mailer.New(mailer.Config{
+		Kind:         os.Getenv("MAILER"),
+		From:         os.Getenv("MAIL_FROM"),
+		Dir:          os.Getenv("MAIL_DIR"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	unverifiedRestrictions, err := parseRestrictions(os.Getenv("UNVERIFIED_RESTRICTIONS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var mfaEncryptionKey []byte
+	if encoded := os.Getenv("MFA_ENCRYPTION_KEY"); encoded != "" {
+		mfaEncryptionKey, err = auth.ParseEncryptionKey(encoded)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	keyring, err := loadKeyring(
+		jwtSecret,
+		os.Getenv("JWT_KEYS_DIR"),
+		os.Getenv("JWT_ACTIVE_KID"),
+		os.Getenv("JWT_RETIRED_KIDS"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	passwordHasher, err := loadPasswordHasher()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Compared against when a login uses an unknown email
+	dummyPasswordHash, err := passwordHasher.Hash("chirpy-dummy-password")
+	if err != nil {
+		log.Fatal(err)
+	}
+	entitlementsService, err := loadEntitlements()
+	if err != nil {
+		log.Fatal(err)
+	}
+	subscriptionExpiryInterval := defaultSubscriptionExpiryInterval
+	if interval := os.Getenv("SUBSCRIPTION_EXPIRY_INTERVAL"); interval != "" {
+		subscriptionExpiryInterval, err = time.ParseDuration(interval)
+		if err != nil || subscriptionExpiryInterval <= 0 {
+			log.Fatal("Invalid SUBSCRIPTION_EXPIRY_INTERVAL")
+		}
+	}
+	accountDeletionGracePeriod, accountDeletionChirps, err := loadAccountDeletionSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	dbQueries := database.New(db)
+	apiCfg.db = db
 	apiCfg.dbQueries = dbQueries
 	apiCfg.platform = platform
-	apiCfg.jwtSecret = jwtSecret
-	apiCfg.polkaKey = polkaKey
+	apiCfg.keyring = keyring
+	apiCfg.paymentProviders = loadPaymentProviders(platform)
+	apiCfg.adminAPIKey = os.Getenv("ADMIN_API_KEY")
+	apiCfg.entitlements = entitlementsService
+	apiCfg.baseURL = baseURL
+	apiCfg.mailer = mail
+	apiCfg.unverifiedRestrictions = unverifiedRestrictions
+	apiCfg.mfaEncryptionKey = mfaEncryptionKey
+	apiCfg.passwordHasher = passwordHasher
+	apiCfg.dummyPasswordHash = dummyPasswordHash
+	apiCfg.trustProxyHeaders = os.Getenv("TRUST_PROXY_HEADERS") == "true"
+	apiCfg.accountDeletionGracePeriod = accountDeletionGracePeriod
+	apiCfg.accountDeletionChirps = accountDeletionChirps
+	eventsBackend := os.Getenv("EVENTS_BACKEND")
+	apiCfg.hub, apiCfg.events, err = newEventBus(eventsBackend, db, dbURL, pubsub.DefaultChannel, streamHistory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.signalHub, apiCfg.signals, err = newEventBus(eventsBackend, db, dbURL, signalsChannel, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	apiCfg.graphql, err = gql.NewServer(dbQueries, gql.DefaultLimits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go apiCfg.runSubscriptionExpiry(context.Background(), subscriptionExpiryInterval)
+	go apiCfg.runWebhookDeliveries(context.Background(), webhookDeliveryInterval)
+	go apiCfg.runDataExports(context.Background(), dataExportInterval)
+	go apiCfg.runAccountDeletions(context.Background(), accountDeletionInterval)
+	go apiCfg.runChirpImports(context.Background(), chirpImportInterval)
+	validator, err := openapi.NewValidator(openapi.Spec)
+	if err != nil {
+		log.Fatal(err)
+	}
 	mux := http.NewServeMux()
 
 	// Create a new http.Server
 	server := &http.Server{
 		Addr:  ":" + port, // Bind to port 8080
-		Handler: mux,     // Use the ServeMux as the handler
+		Handler: apiCfg.middlewareValidate(mux, validator), // Validate requests, then route them with the ServeMux
 	}
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Hello World"))
@@ -113,18 +319,69 @@ func main (){
 	})
 	mux.Handle("/app/",apiCfg.middlewareMetricsInc(handler))
 	mux.HandleFunc("GET /api/healthz", apiCfg.handlerReadiness)
+	mux.HandleFunc("GET /api/openapi.json", apiCfg.handlerOpenAPI)
+	mux.HandleFunc("GET /api/docs", apiCfg.handlerDocs)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handlerJWKS)
 	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUser)
+	mux.HandleFunc("PUT /api/users", apiCfg.middlewareAuth(auth.ScopeProfileWrite, apiCfg.handlerUpdateUser))
 	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.handlerLoginMFA)
+	mux.HandleFunc("POST /api/mfa/totp/enroll", apiCfg.middlewareSessionAuth(apiCfg.handlerEnrollTOTP))
+	mux.HandleFunc("POST /api/mfa/totp/confirm", apiCfg.middlewareSessionAuth(apiCfg.handlerConfirmTOTP))
+	mux.HandleFunc("POST /api/mfa/totp/disable", apiCfg.middlewareSessionAuth(apiCfg.handlerDisableTOTP))
+	mux.HandleFunc("POST /api/tokens", apiCfg.middlewareSessionAuth(apiCfg.handlerCreateToken))
+	mux.HandleFunc("GET /api/tokens", apiCfg.middlewareSessionAuth(apiCfg.handlerListTokens))
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.middlewareSessionAuth(apiCfg.handlerRevokeAccessToken))
+	mux.HandleFunc("POST /api/webhooks", apiCfg.middlewareSessionAuth(apiCfg.handlerCreateWebhook))
+	mux.HandleFunc("GET /api/webhooks", apiCfg.middlewareSessionAuth(apiCfg.handlerListWebhooks))
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiCfg.middlewareSessionAuth(apiCfg.handlerDeleteWebhook))
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiCfg.middlewareSessionAuth(apiCfg.handlerListWebhookDeliveries))
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/retry", apiCfg.middlewareSessionAuth(apiCfg.handlerRetryWebhookDelivery))
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevokeToken)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handlerForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handlerResetPassword)
+	mux.HandleFunc("GET /api/verify-email", apiCfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/verify-email/resend", apiCfg.middlewareAuth(auth.ScopeProfileWrite, apiCfg.handlerResendVerification))
+	mux.HandleFunc("DELETE /api/me", apiCfg.middlewareSessionAuth(apiCfg.handlerDeleteAccount))
+	mux.HandleFunc("POST /api/me/export", apiCfg.middlewareSessionAuth(apiCfg.handlerCreateExport))
+	mux.HandleFunc("GET /api/me/export/{exportID}", apiCfg.handlerGetExport)
+	mux.HandleFunc("POST /api/me/imports", apiCfg.middlewareAuth(auth.ScopeChirpsWrite, apiCfg.handlerCreateChirpImport))
+	mux.HandleFunc("GET /api/me/imports/{importID}", apiCfg.middlewareAuth(auth.ScopeChirpsRead, apiCfg.handlerGetChirpImport))
+	mux.HandleFunc("GET /api/me/entitlements", apiCfg.middlewareAuth(auth.ScopeChirpsRead, apiCfg.handlerGetEntitlements))
+	mux.HandleFunc("POST /api/chirps", apiCfg.middlewareAuth(auth.ScopeChirpsWrite, apiCfg.handlerCreateChirp))
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetChirps)
+	mux.HandleFunc("GET /api/stream/chirps", apiCfg.handlerStreamChirps)
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
+	mux.HandleFunc("POST /graphql", apiCfg.handlerGraphQL)
+	mux.HandleFunc("GET /graphql", apiCfg.handlerGraphQL)
+	// Kept for clients written before GraphQL moved to /graphql
+	mux.HandleFunc("POST /api/graphql", apiCfg.handlerGraphQL)
+	mux.HandleFunc("GET /api/graphql", apiCfg.handlerGraphQL)
+	mux.HandleFunc("GET /users/{userID}/feed.rss", apiCfg.handlerUserFeedRSS)
+	mux.HandleFunc("GET /users/{userID}/feed.atom", apiCfg.handlerUserFeedAtom)
+	mux.HandleFunc("GET /hashtags/{tag}/feed.atom", apiCfg.handlerHashtagFeedAtom)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.middlewareAuth(auth.ScopeChirpsWrite, apiCfg.handlerDeleteChirp))
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlePolkaWebhooks)
+	mux.HandleFunc("POST /api/payments/{provider}/webhooks", apiCfg.handlePaymentWebhooks)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+	mux.HandleFunc("GET /admin/webhooks/events", apiCfg.middlewareAdmin(apiCfg.handlerListWebhookEvents))
+	mux.HandleFunc("POST /admin/webhooks/events/{eventID}/replay", apiCfg.middlewareAdmin(apiCfg.handlerReplayWebhookEvent))
+	grpcHost := os.Getenv("GRPC_HOST")
+	if grpcHost == "" {
+		grpcHost = defaultGRPCHost
+	}
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = defaultGRPCPort
+	}
+	grpcAddr := net.JoinHostPort(grpcHost, grpcPort)
+	go func() {
+		log.Printf("Serving gRPC on %s\n", grpcAddr)
+		log.Fatal(apiCfg.serveGRPC(grpcAddr, os.Getenv("GRPC_TLS_CERT_FILE"), os.Getenv("GRPC_TLS_KEY_FILE")))
+	}()
 	// Start the server
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
 	log.Fatal(server.ListenAndServe())
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/RodolfoCamposGlz/internal/database"
+	"github.com/google/uuid"
+)
+
+// authedHandler is a handler that runs after the caller has been
+// authenticated
+type authedHandler func(http.ResponseWriter, *http.Request, uuid.UUID)
+
+// middlewareAuth accepts either an access JWT or a personal access token
+// that was granted scope
+func (cfg *apiConfig) middlewareAuth(scope auth.Scope, next authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find token")
+			return
+		}
+		userID, apiErr := cfg.authenticateToken(r.Context(), token, scope)
+		if apiErr != nil {
+			respondWithAPIError(w, apiErr)
+			return
+		}
+		next(w, r, userID)
+	}
+}
+
+// authenticateToken returns who an access JWT, or a personal access token
+// that was granted scope, belongs to. The gRPC API checks tokens with it
+// too.
+func (cfg *apiConfig) authenticateToken(ctx context.Context, token string, scope auth.Scope) (uuid.UUID, *apiError) {
+	if !auth.IsPersonalAccessToken(token) {
+		userID, err := cfg.keyring.ValidateJWT(token)
+		if err != nil {
+			return uuid.Nil, &apiError{status: http.StatusUnauthorized, message: "Invalid JWT"}
+		}
+		return userID, nil
+	}
+
+	pat, err := cfg.dbQueries.GetPersonalAccessTokenByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return uuid.Nil, &apiError{status: http.StatusUnauthorized, message: "Invalid token"}
+	}
+	if pat.RevokedAt.Valid {
+		return uuid.Nil, &apiError{status: http.StatusUnauthorized, message: "Token revoked"}
+	}
+	if pat.ExpiresAt.Valid && pat.ExpiresAt.Time.Before(time.Now()) {
+		return uuid.Nil, &apiError{status: http.StatusUnauthorized, message: "Token expired"}
+	}
+	if !auth.HasScope(pat.Scopes, scope) {
+		return uuid.Nil, &apiError{status: http.StatusForbidden, message: fmt.Sprintf("Token is missing the %s scope", scope)}
+	}
+
+	err = cfg.dbQueries.TouchPersonalAccessToken(ctx, pat.ID)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+	}
+	return pat.UserID, nil
+}
+
+// middlewareSessionAuth only accepts access JWTs. It guards account
+// security settings, such as managing tokens, that a leaked personal access
+// token must not be able to change.
+func (cfg *apiConfig) middlewareSessionAuth(next authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT")
+			return
+		}
+		userID, err := cfg.keyring.ValidateJWT(token)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Invalid JWT")
+			return
+		}
+		next(w, r, userID)
+	}
+}
+
+// middlewareAdmin guards operator endpoints. Operators send the
+// ADMIN_API_KEY as "Authorization: ApiKey <key>", users granted the admin
+// role send an access JWT. Without a key, only admins can use them.
+func (cfg *apiConfig) middlewareAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err == nil {
+			apiErr := cfg.authenticateAdmin(r.Context(), token)
+			if apiErr != nil {
+				respondWithAPIError(w, apiErr)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		if cfg.adminAPIKey == "" {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT")
+			return
+		}
+		_, err = auth.GetAPIKey(r.Header, cfg.adminAPIKey)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authenticateAdmin checks that token is an access JWT of an admin. Like
+// account security settings, personal access tokens can't be used.
+func (cfg *apiConfig) authenticateAdmin(ctx context.Context, token string) *apiError {
+	if auth.IsPersonalAccessToken(token) {
+		return &apiError{status: http.StatusForbidden, message: "Personal access tokens can't use admin endpoints"}
+	}
+	userID, err := cfg.keyring.ValidateJWT(token)
+	if err != nil {
+		return &apiError{status: http.StatusUnauthorized, message: "Invalid JWT"}
+	}
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return &apiError{status: http.StatusUnauthorized, message: "Invalid JWT"}
+	}
+	if !hasRole(user, roleAdmin) {
+		return &apiError{status: http.StatusForbidden, message: "Admin role required"}
+	}
+	return nil
+}
+
+// hasRole reports whether user was granted any of roles. Suspended users
+// keep their roles but can't use them.
+func hasRole(user database.User, roles ...string) bool {
+	if user.SuspendedAt.Valid {
+		return false
+	}
+	for _, role := range roles {
+		if slices.Contains(user.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/RodolfoCamposGlz/internal/openapi"
+)
+
+type validationErrorResponse struct {
+	Error  string               `json:"error"`
+	Errors []openapi.FieldError `json:"errors"`
+}
+
+// middlewareValidate checks requests against the OpenAPI document before
+// mux hands them to a handler, so handlers only see bodies, path and query
+// parameters of the documented shape
+func (cfg *apiConfig) middlewareValidate(mux *http.ServeMux, validator *openapi.Validator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if errs := validator.ValidateRequest(r, pattern); len(errs) > 0 {
+			respondWithJSON(w, http.StatusBadRequest, validationErrorResponse{
+				Error:  "Invalid request",
+				Errors: errs,
+			})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
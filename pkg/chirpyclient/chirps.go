@@ -0,0 +1,240 @@
+package chirpyclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sort orders
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// CreateChirp posts a chirp as the caller
+func (c *Client) CreateChirp(ctx context.Context, body string) (*Chirp, error) {
+	chirp := &Chirp{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/chirps",
+		body:   map[string]string{"body": body},
+		auth:   authUser,
+	}, chirp)
+	if err != nil {
+		return nil, err
+	}
+	return chirp, nil
+}
+
+// GetChirp returns one chirp
+func (c *Client) GetChirp(ctx context.Context, chirpID uuid.UUID) (*Chirp, error) {
+	chirp := &Chirp{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/chirps/" + chirpID.String()}, chirp)
+	if err != nil {
+		return nil, err
+	}
+	return chirp, nil
+}
+
+// ListChirpsParams filter and order ListChirps and Chirps
+type ListChirpsParams struct {
+	// AuthorID only returns chirps by this user when set
+	AuthorID uuid.UUID
+	// Sort is SortAsc, the default, or SortDesc
+	Sort string
+}
+
+// ListChirps returns every matching chirp in one response. Chirps pages
+// through them instead.
+func (c *Client) ListChirps(ctx context.Context, params ListChirpsParams) ([]Chirp, error) {
+	query := url.Values{}
+	if params.AuthorID != uuid.Nil {
+		query.Set("author_id", params.AuthorID.String())
+	}
+	if params.Sort != "" {
+		query.Set("sort", params.Sort)
+	}
+	chirps := []Chirp{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/chirps", query: query}, &chirps)
+	if err != nil {
+		return nil, err
+	}
+	return chirps, nil
+}
+
+// DeleteChirp deletes one of the caller's chirps
+func (c *Client) DeleteChirp(ctx context.Context, chirpID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/chirps/" + chirpID.String(), auth: authUser}, nil)
+}
+
+// importContentTypes are sent with each archive format
+var importContentTypes = map[string]string{
+	ImportJSONL: "application/x-ndjson",
+	ImportCSV:   "text/csv",
+}
+
+// ImportChirps uploads an archive of chirps with their original
+// timestamps, as JSON Lines or CSV. Poll GetChirpImport until its status
+// isn't ImportPending for the result.
+func (c *Client) ImportChirps(ctx context.Context, format string, archive []byte) (*ChirpImport, error) {
+	contentType, ok := importContentTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+	chirpImport := &ChirpImport{}
+	err := c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/me/imports",
+		body:        archive,
+		contentType: contentType,
+		auth:        authUser,
+	}, chirpImport)
+	if err != nil {
+		return nil, err
+	}
+	return chirpImport, nil
+}
+
+// GetChirpImport returns an import's status and, once it's done, the result
+func (c *Client) GetChirpImport(ctx context.Context, importID uuid.UUID) (*ChirpImport, error) {
+	chirpImport := &ChirpImport{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/me/imports/" + importID.String(), auth: authUser}, chirpImport)
+	if err != nil {
+		return nil, err
+	}
+	return chirpImport, nil
+}
+
+// chirpsPageSize is the most chirps the GraphQL API returns at once
+const chirpsPageSize = 100
+
+const chirpsQuery = `query Chirps($first: Int, $after: String, $order: ChirpOrder, $authorId: ID) {
+  chirps(first: $first, after: $after, order: $order, authorId: $authorId) {
+    edges { node { id body createdAt updatedAt author { id } } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+// Chirps iterates over every matching chirp, fetching them a page at a
+// time. Iteration stops at the first error.
+//
+//	for chirp, err := range client.Chirps(ctx, chirpyclient.ListChirpsParams{}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *Client) Chirps(ctx context.Context, params ListChirpsParams) iter.Seq2[Chirp, error] {
+	return func(yield func(Chirp, error) bool) {
+		variables := map[string]any{"first": chirpsPageSize}
+		if params.AuthorID != uuid.Nil {
+			variables["authorId"] = params.AuthorID.String()
+		}
+		if params.Sort == SortDesc {
+			variables["order"] = "DESC"
+		}
+
+		for {
+			page := struct {
+				Chirps struct {
+					Edges []struct {
+						Node struct {
+							ID        uuid.UUID `json:"id"`
+							Body      string    `json:"body"`
+							CreatedAt time.Time `json:"createdAt"`
+							UpdatedAt time.Time `json:"updatedAt"`
+							Author    struct {
+								ID uuid.UUID `json:"id"`
+							} `json:"author"`
+						} `json:"node"`
+					} `json:"edges"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"chirps"`
+			}{}
+			err := c.GraphQL(ctx, chirpsQuery, variables, &page)
+			if err != nil {
+				yield(Chirp{}, err)
+				return
+			}
+			for _, edge := range page.Chirps.Edges {
+				chirp := Chirp{
+					ID:        edge.Node.ID,
+					Body:      edge.Node.Body,
+					UserID:    edge.Node.Author.ID,
+					CreatedAt: edge.Node.CreatedAt,
+					UpdatedAt: edge.Node.UpdatedAt,
+				}
+				if !yield(chirp, nil) {
+					return
+				}
+			}
+			if !page.Chirps.PageInfo.HasNextPage {
+				return
+			}
+			variables["after"] = page.Chirps.PageInfo.EndCursor
+		}
+	}
+}
+
+// GraphQL runs a query and decodes its data into out. The caller's token is
+// sent when the client has one. Errors in the result are returned as
+// GraphQLErrors after decoding whatever data there is.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) error {
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/graphql",
+		body:   map[string]any{"query": query, "variables": variables},
+		auth:   authOptional,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(result.Data) > 0 && string(result.Data) != "null" {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return err
+		}
+	}
+	if len(result.Errors) > 0 {
+		return result.Errors
+	}
+	return nil
+}
+
+// UserFeedRSS returns a user's latest chirps as an RSS 2.0 document
+func (c *Client) UserFeedRSS(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	return c.feed(ctx, "/users/"+userID.String()+"/feed.rss")
+}
+
+// UserFeedAtom returns a user's latest chirps as an Atom document
+func (c *Client) UserFeedAtom(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	return c.feed(ctx, "/users/"+userID.String()+"/feed.atom")
+}
+
+// HashtagFeedAtom returns the latest chirps with a hashtag, without the #,
+// as an Atom document
+func (c *Client) HashtagFeedAtom(ctx context.Context, tag string) ([]byte, error) {
+	return c.feed(ctx, "/hashtags/"+url.PathEscape(tag)+"/feed.atom")
+}
+
+func (c *Client) feed(ctx context.Context, path string) ([]byte, error) {
+	var body []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: path}, &body)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
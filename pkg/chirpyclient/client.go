@@ -0,0 +1,348 @@
+// Package chirpyclient is a Go client for the Chirpy API.
+//
+//	client := chirpyclient.New("https://chirpy.example.com")
+//	_, err := client.Login(ctx, "user@example.com", "hunter2")
+//	...
+//	chirp, err := client.CreateChirp(ctx, "Hello, Chirpy")
+//
+// After Login the client sends the access token with every call that needs
+// one, and when the server rejects an expired token it gets a new one from
+// /api/refresh and tries again. GET, PUT and DELETE calls are retried with
+// backoff when the server is unavailable or rate limits them. Errors
+// returned by the server are *APIError.
+package chirpyclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 10 * time.Second
+)
+
+// Client calls the Chirpy API. It's safe for concurrent use.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	adminAPIKey string
+	maxRetries  int
+	backoff     time.Duration
+	onRefresh   func(accessToken string)
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests with httpClient instead of
+// http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokens starts the client with tokens from an earlier login. The
+// refresh token can be empty, then expired access tokens aren't renewed.
+func WithTokens(accessToken, refreshToken string) Option {
+	return func(c *Client) {
+		c.accessToken = accessToken
+		c.refreshToken = refreshToken
+	}
+}
+
+// WithPersonalAccessToken authenticates with a personal access token
+// instead of logging in
+func WithPersonalAccessToken(token string) Option {
+	return WithTokens(token, "")
+}
+
+// WithAdminAPIKey sets the key the admin endpoints need
+func WithAdminAPIKey(key string) Option {
+	return func(c *Client) {
+		c.adminAPIKey = key
+	}
+}
+
+// WithRetries sets how many times idempotent calls are retried, and the
+// backoff before the first retry, which doubles with every attempt
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithTokenRefreshHandler calls f with every access token the client gets
+// from /api/refresh, so it can be saved
+func WithTokenRefreshHandler(f func(accessToken string)) Option {
+	return func(c *Client) {
+		c.onRefresh = f
+	}
+}
+
+// New returns a client for the server at baseURL, such as
+// https://chirpy.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Tokens returns the current access and refresh tokens
+func (c *Client) Tokens() (accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accessToken, c.refreshToken
+}
+
+func (c *Client) setTokens(accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = accessToken
+	c.refreshToken = refreshToken
+}
+
+// authKind is the credential a request is sent with
+type authKind int
+
+const (
+	authNone authKind = iota
+	// authUser sends the access token, refreshing it once when it's
+	// rejected
+	authUser
+	// authOptional sends the access token when there is one
+	authOptional
+	authRefresh
+	authAdmin
+)
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	auth   authKind
+	// contentType sends body, a []byte, as it is instead of as JSON
+	contentType string
+}
+
+// idempotent requests can be sent again without changing the result
+func (r request) idempotent() bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// do sends req and decodes the response into out. out can be nil to ignore
+// the response, or a *[]byte for the raw body.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	var body []byte
+	if raw, ok := req.body.([]byte); ok && req.contentType != "" {
+		body = raw
+	} else if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		credential := c.credential(req.auth)
+		resp, err := c.send(ctx, req, body, credential)
+		if err != nil {
+			if ctx.Err() != nil || !req.idempotent() || attempt >= c.maxRetries {
+				return err
+			}
+			if err := c.wait(ctx, attempt, 0); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && req.auth == authUser && !refreshed && c.canRefresh() {
+			resp.Body.Close()
+			refreshed = true
+			if err := c.refresh(ctx, credential); err != nil {
+				return err
+			}
+			attempt--
+			continue
+		}
+
+		if retryable(resp.StatusCode) && req.idempotent() && attempt < c.maxRetries {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			if err := c.wait(ctx, attempt, retryAfter); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return decodeResponse(resp, out)
+	}
+}
+
+// credential returns the Authorization header for kind
+func (c *Client) credential(kind authKind) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch kind {
+	case authUser, authOptional:
+		if c.accessToken != "" {
+			return "Bearer " + c.accessToken
+		}
+	case authRefresh:
+		if c.refreshToken != "" {
+			return "Bearer " + c.refreshToken
+		}
+	case authAdmin:
+		return "ApiKey " + c.adminAPIKey
+	}
+	return ""
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte, credential string) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if credential != "" {
+		httpReq.Header.Set("Authorization", credential)
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+func (c *Client) canRefresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken != ""
+}
+
+// refresh gets a new access token after the server rejected credential.
+// Calls that failed with the same token wait for one refresh instead of
+// each making their own.
+func (c *Client) refresh(ctx context.Context, credential string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if credential != "" && credential != "Bearer "+c.accessToken {
+		// Refreshed by another call in the meantime
+		return nil
+	}
+	if c.refreshToken == "" {
+		return &APIError{StatusCode: http.StatusUnauthorized, Message: "No refresh token"}
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: "/api/refresh"}, nil, "Bearer "+c.refreshToken)
+	if err != nil {
+		return err
+	}
+	token := struct {
+		Token string `json:"token"`
+	}{}
+	if err := decodeResponse(resp, &token); err != nil {
+		return err
+	}
+	c.accessToken = token.Token
+	if c.onRefresh != nil {
+		c.onRefresh(token.Token)
+	}
+	return nil
+}
+
+// wait sleeps before retry attempt, for retryAfter when the server asked
+// for it
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := min(retryAfter, maxBackoff)
+	if delay <= 0 {
+		delay = min(c.backoff<<attempt, maxBackoff)
+		// Jitter so clients that failed together don't retry together
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp)
+	}
+	if raw, ok := out.(*[]byte); ok {
+		body, err := io.ReadAll(resp.Body)
+		*raw = body
+		return err
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	err := json.NewDecoder(resp.Body).Decode(out)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decoding %s response: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
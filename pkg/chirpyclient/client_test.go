@@ -0,0 +1,337 @@
+package chirpyclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestClient(t *testing.T, handler http.Handler, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	opts = append([]Option{WithRetries(3, time.Millisecond)}, opts...)
+	return New(server.URL, opts...)
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func TestRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"id": uuid.New(), "token": "expired", "refresh_token": "refresh"})
+	})
+	mux.HandleFunc("POST /api/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer refresh" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid refresh token"})
+			return
+		}
+		refreshes.Add(1)
+		writeJSON(w, http.StatusOK, map[string]string{"token": "fresh"})
+	})
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid JWT"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{"id": uuid.New(), "body": "Hello"})
+	})
+
+	var saved string
+	client := newTestClient(t, mux, WithTokenRefreshHandler(func(token string) { saved = token }))
+	ctx := context.Background()
+	_, err := client.Login(ctx, "user@example.com", "hunter2")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	chirp, err := client.CreateChirp(ctx, "Hello")
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	if chirp.Body != "Hello" {
+		t.Errorf("CreateChirp() body = %q, want %q", chirp.Body, "Hello")
+	}
+	if refreshes.Load() != 1 || saved != "fresh" {
+		t.Errorf("refreshed %d times and saved %q, want once and %q", refreshes.Load(), saved, "fresh")
+	}
+	if access, _ := client.Tokens(); access != "fresh" {
+		t.Errorf("access token = %q, want %q", access, "fresh")
+	}
+
+	// Without a refresh token the rejection is returned
+	client = newTestClient(t, mux, WithPersonalAccessToken("chirpy_pat_revoked"))
+	_, err = client.CreateChirp(ctx, "Hello")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("CreateChirp() with a rejected token error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		responses []int
+		wantCalls int32
+		wantErr   error
+	}{
+		{
+			name:      "GET retried until it succeeds",
+			method:    http.MethodGet,
+			responses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantCalls: 3,
+		},
+		{
+			name:      "GET gives up after the retries",
+			method:    http.MethodGet,
+			responses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantCalls: 4,
+			wantErr:   ErrServer,
+		},
+		{
+			name:      "GET not retried for client errors",
+			method:    http.MethodGet,
+			responses: []int{http.StatusNotFound, http.StatusOK},
+			wantCalls: 1,
+			wantErr:   ErrNotFound,
+		},
+		{
+			name:      "DELETE retried",
+			method:    http.MethodDelete,
+			responses: []int{http.StatusServiceUnavailable, http.StatusNoContent},
+			wantCalls: 2,
+		},
+		{
+			name:      "POST not retried",
+			method:    http.MethodPost,
+			responses: []int{http.StatusServiceUnavailable, http.StatusCreated},
+			wantCalls: 1,
+			wantErr:   ErrServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				code := tt.responses[calls.Add(1)-1]
+				if code >= 400 {
+					writeJSON(w, code, map[string]string{"error": http.StatusText(code)})
+					return
+				}
+				writeJSON(w, code, map[string]any{"id": uuid.New()})
+			}), WithTokens("token", ""))
+
+			var err error
+			ctx := context.Background()
+			switch tt.method {
+			case http.MethodGet:
+				_, err = client.GetChirp(ctx, uuid.New())
+			case http.MethodDelete:
+				err = client.DeleteChirp(ctx, uuid.New())
+			case http.MethodPost:
+				_, err = client.CreateChirp(ctx, "Hello")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error": "Invalid request",
+			"errors": []map[string]string{
+				{"in": "body", "field": "email", "message": "must be an email address"},
+			},
+		})
+	})
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "Too many failed login attempts, try again later"})
+	})
+	mux.HandleFunc("POST /api/login/mfa", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"mfa_required": true, "mfa_token": "challenge"})
+	})
+	client := newTestClient(t, mux)
+	ctx := context.Background()
+
+	_, err := client.CreateUser(ctx, "not-an-email", "hunter2")
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("CreateUser() error = %v, want an *APIError for a bad request", err)
+	}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "email" {
+		t.Errorf("CreateUser() field errors = %+v, want one for email", apiErr.Errors)
+	}
+
+	_, err = client.Login(ctx, "user@example.com", "wrong")
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 30*time.Second {
+		t.Errorf("Login() error = %v, want a 429 with a 30s Retry-After", err)
+	}
+
+	_, err = client.LoginMFA(ctx, "challenge", "123456")
+	mfaErr := &MFARequiredError{}
+	if !errors.As(err, &mfaErr) || mfaErr.MFAToken != "challenge" {
+		t.Errorf("LoginMFA() error = %v, want an *MFARequiredError", err)
+	}
+}
+
+// graphQLChirps serves the chirps connection over total chirps, with the
+// index of the last chirp as the cursor
+func graphQLChirps(total int, requests *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		req := struct {
+			Variables struct {
+				First int    `json:"first"`
+				After string `json:"after"`
+			} `json:"variables"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		start := 0
+		if req.Variables.After != "" {
+			start, _ = strconv.Atoi(req.Variables.After)
+			start++
+		}
+		end := min(start+req.Variables.First, total)
+		edges := []map[string]any{}
+		for i := start; i < end; i++ {
+			edges = append(edges, map[string]any{"node": map[string]any{
+				"id":        uuid.New(),
+				"body":      strconv.Itoa(i),
+				"createdAt": time.Now(),
+				"updatedAt": time.Now(),
+				"author":    map[string]any{"id": uuid.New()},
+			}})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{"chirps": map[string]any{
+			"edges":    edges,
+			"pageInfo": map[string]any{"hasNextPage": end < total, "endCursor": strconv.Itoa(end - 1)},
+		}}})
+	})
+}
+
+func TestChirpsPagination(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, graphQLChirps(250, &requests))
+	ctx := context.Background()
+
+	seen := 0
+	for chirp, err := range client.Chirps(ctx, ListChirpsParams{}) {
+		if err != nil {
+			t.Fatalf("Chirps() error = %v", err)
+		}
+		if chirp.Body != strconv.Itoa(seen) {
+			t.Fatalf("chirp %d has body %q", seen, chirp.Body)
+		}
+		seen++
+	}
+	if seen != 250 || requests.Load() != 3 {
+		t.Errorf("iterated over %d chirps in %d requests, want 250 in 3", seen, requests.Load())
+	}
+
+	// Breaking out stops fetching pages
+	requests.Store(0)
+	for range client.Chirps(ctx, ListChirpsParams{}) {
+		break
+	}
+	if requests.Load() != 1 {
+		t.Errorf("fetched %d pages after breaking on the first chirp, want 1", requests.Load())
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"data":   map[string]any{"viewer": nil},
+			"errors": []map[string]any{{"message": "query is nested 12 levels deep, the limit is 10"}},
+		})
+	}))
+
+	data := struct {
+		Viewer *User `json:"viewer"`
+	}{}
+	err := client.GraphQL(context.Background(), "{ viewer { id } }", nil, &data)
+	gqlErrs := GraphQLErrors{}
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 1 {
+		t.Errorf("GraphQL() error = %v, want GraphQLErrors", err)
+	}
+}
+
+func TestDownloadExport(t *testing.T) {
+	exportID := uuid.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/me/export/{exportID}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "signed" || r.Header.Get("Authorization") != "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "Invalid or expired download link"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("PK"))
+	})
+	client := newTestClient(t, mux, WithTokens("token", ""))
+	ctx := context.Background()
+
+	// The link is followed on the client's server, whatever host it names
+	archive, err := client.DownloadExport(ctx, &DataExport{
+		ID:          exportID,
+		Status:      ExportReady,
+		DownloadURL: "https://chirpy.example.com/api/me/export/" + exportID.String() + "?token=signed",
+	})
+	if err != nil || string(archive) != "PK" {
+		t.Errorf("DownloadExport() = %q, %v, want the archive", archive, err)
+	}
+
+	_, err = client.DownloadExport(ctx, &DataExport{ID: exportID, Status: ExportPending})
+	if err == nil {
+		t.Errorf("DownloadExport() of a pending export should fail")
+	}
+}
+
+func TestImportChirps(t *testing.T) {
+	archive := "created_at,body\n2019-03-01T09:30:00Z,Hello\n"
+	importID := uuid.New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/me/imports", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "text/csv" || string(body) != archive {
+			writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Unsupported archive"})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, ChirpImport{ID: importID, Status: ImportPending, Format: ImportCSV})
+	})
+	client := newTestClient(t, mux, WithTokens("token", ""))
+	ctx := context.Background()
+
+	chirpImport, err := client.ImportChirps(ctx, ImportCSV, []byte(archive))
+	if err != nil || chirpImport.ID != importID || chirpImport.Status != ImportPending {
+		t.Errorf("ImportChirps() = %+v, %v, want the pending import", chirpImport, err)
+	}
+
+	_, err = client.ImportChirps(ctx, "xml", []byte("<chirps/>"))
+	if err == nil {
+		t.Errorf("ImportChirps() with an unknown format should fail")
+	}
+}
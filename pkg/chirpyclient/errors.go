@@ -0,0 +1,121 @@
+package chirpyclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Errors an *APIError matches with errors.Is, by status code
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrServer          = errors.New("server error")
+)
+
+// APIError is an error response from the server
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Errors lists every problem with a request that didn't match the API
+	// description
+	Errors []FieldError
+	// RetryAfter is how long the server asked to wait, for rate limited
+	// calls
+	RetryAfter time.Duration
+}
+
+// FieldError is a problem with one part of a request
+type FieldError struct {
+	// In is body, query or path
+	In string `json:"in"`
+	// Field is the parameter, or the path to the value in the body such as
+	// events[0]
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("chirpy: %d %s", e.StatusCode, e.Message)
+	}
+	msg := fmt.Sprintf("chirpy: %d %s:", e.StatusCode, e.Message)
+	for i, fieldErr := range e.Errors {
+		if i > 0 {
+			msg += ";"
+		}
+		msg += fmt.Sprintf(" %s %s %s", fieldErr.In, fieldErr.Field, fieldErr.Message)
+	}
+	return msg
+}
+
+// Is matches the Err variable for the status code
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrBadRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusTooManyRequests:
+		return target == ErrTooManyRequests
+	}
+	return e.StatusCode >= 500 && target == ErrServer
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	body := struct {
+		Error  string       `json:"error"`
+		Errors []FieldError `json:"errors"`
+	}{}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+		apiErr.Errors = body.Errors
+	}
+	return apiErr
+}
+
+// MFARequiredError is returned by Login for users with two-factor
+// enabled. Finish logging in with LoginMFA.
+type MFARequiredError struct {
+	MFAToken string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "chirpy: two-factor code required"
+}
+
+// GraphQLError is an error in the errors of a GraphQL result
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// GraphQLErrors is returned by GraphQL when the result has errors. Data
+// that could be resolved is still decoded.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	if len(e) == 1 {
+		return "chirpy: graphql: " + e[0].Message
+	}
+	return fmt.Sprintf("chirpy: graphql: %s (and %d more errors)", e[0].Message, len(e)-1)
+}
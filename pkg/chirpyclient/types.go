@@ -0,0 +1,183 @@
+package chirpyclient
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is an account
+type User struct {
+	ID              uuid.UUID `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Email           string    `json:"email"`
+	IsChirpyRed     bool      `json:"is_chirpy_red"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+	// PendingEmail is a new email waiting to be confirmed
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+// Chirp is a short message
+type Chirp struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Scopes a personal access token can be granted
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeProfileWrite = "profile:write"
+)
+
+// PersonalAccessToken is a long-lived token for scripts and integrations
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only set when the token is created
+	Token string `json:"token,omitempty"`
+}
+
+// TOTPEnrollment is a secret to add to an authenticator app
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// Entitlements are what a user's plan allows
+type Entitlements struct {
+	Plan           string `json:"plan"`
+	MaxChirpLength int    `json:"max_chirp_length"`
+	// EditWindowSeconds is 0 when chirps can't be edited
+	EditWindowSeconds int    `json:"edit_window_seconds"`
+	MaxMediaPerChirp  int    `json:"max_media_per_chirp"`
+	ChirpsPerHour     int    `json:"chirps_per_hour"`
+	ScheduledChirps   bool   `json:"scheduled_chirps"`
+	Badge             string `json:"badge"`
+}
+
+// Data export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// DataExport is an archive of a user's data being built or ready to
+// download
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	// ExpiresAt is when the archive is deleted
+	ExpiresAt *time.Time `json:"expires_at"`
+	// DownloadURL is only set while the export is ready. It works without
+	// an access token until DownloadURLExpiresAt.
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// Chirp import archive formats
+const (
+	ImportJSONL = "jsonl"
+	ImportCSV   = "csv"
+)
+
+// Chirp import statuses
+const (
+	ImportPending   = "pending"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ChirpImport is an archive of chirps being imported
+type ChirpImport struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+	Format string    `json:"format"`
+	// Error is why the whole import failed
+	Error         string `json:"error,omitempty"`
+	TotalRows     int    `json:"total_rows"`
+	ImportedRows  int    `json:"imported_rows"`
+	DuplicateRows int    `json:"duplicate_rows"`
+	FailedRows    int    `json:"failed_rows"`
+	// Errors explains why rows failed, for up to 1000 of them
+	Errors      []ImportRowError `json:"errors"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at"`
+}
+
+// ImportRowError is why the chirp on Line of an archive wasn't imported
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Webhook events
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+)
+
+// Webhook is a URL subscribed to events
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret signs deliveries, it's only set when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookDelivery is an attempt to send an event to a webhook
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// WebhookEvent is a webhook received from a payment provider
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	Provider    string          `json:"provider"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error"`
+	Attempts    int             `json:"attempts"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+// JWK is a public key access tokens can be signed with
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
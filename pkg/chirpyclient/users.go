@@ -0,0 +1,278 @@
+package chirpyclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessionResponse is a UserResponse, or a challenge for users with
+// two-factor enabled
+type sessionResponse struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	MFARequired  bool   `json:"mfa_required"`
+	MFAToken     string `json:"mfa_token"`
+}
+
+// CreateUser registers a user. They are sent a link to verify their email.
+func (c *Client) CreateUser(ctx context.Context, email, password string) (*User, error) {
+	user := &User{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/users",
+		body:   map[string]string{"email": email, "password": password},
+	}, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUserParams are the changes to make to the caller. Empty fields are
+// left unchanged.
+type UpdateUserParams struct {
+	// Email only replaces the current one once it has been confirmed
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+	// CurrentPassword is required to change Email or Password, along with
+	// Code when two-factor authentication is enabled. Personal access tokens
+	// can't change either.
+	CurrentPassword string `json:"current_password,omitempty"`
+	Code            string `json:"code,omitempty"`
+}
+
+// UpdateUser changes the caller's email or password
+func (c *Client) UpdateUser(ctx context.Context, params UpdateUserParams) (*User, error) {
+	user := &User{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/users", body: params, auth: authUser}, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login starts a session, later calls are made as the user. Users with
+// two-factor enabled get a *MFARequiredError with a token for LoginMFA.
+func (c *Client) Login(ctx context.Context, email, password string) (*User, error) {
+	return c.startSession(ctx, "/api/login", map[string]string{"email": email, "password": password})
+}
+
+// LoginMFA finishes logging in with a TOTP or recovery code
+func (c *Client) LoginMFA(ctx context.Context, mfaToken, code string) (*User, error) {
+	return c.startSession(ctx, "/api/login/mfa", map[string]string{"mfa_token": mfaToken, "code": code})
+}
+
+func (c *Client) startSession(ctx context.Context, path string, body any) (*User, error) {
+	session := sessionResponse{}
+	err := c.do(ctx, request{method: http.MethodPost, path: path, body: body}, &session)
+	if err != nil {
+		return nil, err
+	}
+	if session.MFARequired {
+		return nil, &MFARequiredError{MFAToken: session.MFAToken}
+	}
+	c.setTokens(session.Token, session.RefreshToken)
+	return &session.User, nil
+}
+
+// Refresh replaces the access token using the refresh token. Calls do this
+// on their own when the access token expires.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.refresh(ctx, "")
+}
+
+// Logout revokes the refresh token and forgets both tokens
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/revoke", auth: authRefresh}, nil)
+	if err != nil {
+		return err
+	}
+	c.setTokens("", "")
+	return nil
+}
+
+// EnrollTOTP generates a two-factor secret. It's enabled once ConfirmTOTP
+// gets a code from it.
+func (c *Client) EnrollTOTP(ctx context.Context) (*TOTPEnrollment, error) {
+	enrollment := &TOTPEnrollment{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/mfa/totp/enroll", auth: authUser}, enrollment)
+	if err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmTOTP enables two-factor and returns single-use recovery codes
+func (c *Client) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	resp := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/mfa/totp/confirm",
+		body:   map[string]string{"code": code},
+		auth:   authUser,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.RecoveryCodes, nil
+}
+
+// DisableTOTP turns two-factor off
+func (c *Client) DisableTOTP(ctx context.Context, password, code string) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/mfa/totp/disable",
+		body:   map[string]string{"password": password, "code": code},
+		auth:   authUser,
+	}, nil)
+}
+
+// CreateTokenParams describe a personal access token
+type CreateTokenParams struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays is 0 for a token that doesn't expire
+	ExpiresInDays int `json:"expires_in_days,omitempty"`
+}
+
+// CreateToken creates a personal access token. Its Token is only returned
+// here.
+func (c *Client) CreateToken(ctx context.Context, params CreateTokenParams) (*PersonalAccessToken, error) {
+	token := &PersonalAccessToken{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/tokens", body: params, auth: authUser}, token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ListTokens returns the caller's active personal access tokens
+func (c *Client) ListTokens(ctx context.Context) ([]PersonalAccessToken, error) {
+	tokens := []PersonalAccessToken{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/tokens", auth: authUser}, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken revokes a personal access token
+func (c *Client) RevokeToken(ctx context.Context, tokenID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/tokens/" + tokenID.String(), auth: authUser}, nil)
+}
+
+// ForgotPassword emails a password reset token, if the account exists
+func (c *Client) ForgotPassword(ctx context.Context, email string) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/password/forgot",
+		body:   map[string]string{"email": email},
+	}, nil)
+}
+
+// ResetPassword sets a new password with a reset token. Every session of
+// the user is signed out.
+func (c *Client) ResetPassword(ctx context.Context, token, password string) error {
+	return c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/password/reset",
+		body:   map[string]string{"token": token, "password": password},
+	}, nil)
+}
+
+// VerifyEmail confirms an email address with the token from the link sent
+// to it
+func (c *Client) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	user := &User{}
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/verify-email",
+		query:  url.Values{"token": {token}},
+	}, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResendVerification sends the caller's verification link again
+func (c *Client) ResendVerification(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/verify-email/resend", auth: authUser}, nil)
+}
+
+// Entitlements returns what the caller's plan allows
+func (c *Client) Entitlements(ctx context.Context) (*Entitlements, error) {
+	entitlements := &Entitlements{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/me/entitlements", auth: authUser}, entitlements)
+	if err != nil {
+		return nil, err
+	}
+	return entitlements, nil
+}
+
+// DeleteAccount schedules the caller's account for deletion and returns
+// when it will happen. code is only needed with two-factor enabled. Every
+// session is signed out, logging in again before then cancels the deletion.
+func (c *Client) DeleteAccount(ctx context.Context, password, code string) (time.Time, error) {
+	body := map[string]string{"password": password}
+	if code != "" {
+		body["code"] = code
+	}
+	deletion := struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}{}
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/api/me", body: body, auth: authUser}, &deletion)
+	if err != nil {
+		return time.Time{}, err
+	}
+	c.setTokens("", "")
+	return deletion.DeleteAfter, nil
+}
+
+// CreateExport starts bundling the caller's data into a zip archive. Poll
+// GetExport until its status is ExportReady, then call DownloadExport.
+func (c *Client) CreateExport(ctx context.Context) (*DataExport, error) {
+	dataExport := &DataExport{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/me/export", auth: authUser}, dataExport)
+	if err != nil {
+		return nil, err
+	}
+	return dataExport, nil
+}
+
+// GetExport returns an export's status, with a fresh download link once it's
+// ready
+func (c *Client) GetExport(ctx context.Context, exportID uuid.UUID) (*DataExport, error) {
+	dataExport := &DataExport{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/me/export/" + exportID.String(), auth: authUser}, dataExport)
+	if err != nil {
+		return nil, err
+	}
+	return dataExport, nil
+}
+
+// DownloadExport returns the zip archive of a ready export. The link is
+// fetched from the client's server, whatever host it names.
+func (c *Client) DownloadExport(ctx context.Context, dataExport *DataExport) ([]byte, error) {
+	if dataExport.DownloadURL == "" {
+		return nil, fmt.Errorf("export %s is %s, not ready", dataExport.ID, dataExport.Status)
+	}
+	link, err := url.Parse(dataExport.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	var archive []byte
+	err = c.do(ctx, request{method: http.MethodGet, path: link.Path, query: link.Query()}, &archive)
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
@@ -0,0 +1,141 @@
+package chirpyclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// CreateWebhook subscribes a URL to events. The returned Secret, which
+// signs deliveries, is only returned here.
+func (c *Client) CreateWebhook(ctx context.Context, url string, events []string) (*Webhook, error) {
+	webhook := &Webhook{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/webhooks",
+		body:   map[string]any{"url": url, "events": events},
+		auth:   authUser,
+	}, webhook)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the caller's webhooks
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/webhooks", auth: authUser}, &webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/webhooks/" + webhookID.String(), auth: authUser}, nil)
+}
+
+// ListParams filter a log. Zero values use the server's defaults.
+type ListParams struct {
+	Status string
+	Limit  int
+}
+
+func (p ListParams) query() url.Values {
+	query := url.Values{}
+	if p.Status != "" {
+		query.Set("status", p.Status)
+	}
+	if p.Limit > 0 {
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return query
+}
+
+// ListWebhookDeliveries returns a webhook's deliveries, newest first
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, params ListParams) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/webhooks/" + webhookID.String() + "/deliveries",
+		query:  params.query(),
+		auth:   authUser,
+	}, &deliveries)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery gives a dead delivery a fresh set of attempts
+func (c *Client) RetryWebhookDelivery(ctx context.Context, webhookID, deliveryID uuid.UUID) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/webhooks/" + webhookID.String() + "/deliveries/" + deliveryID.String() + "/retry",
+		auth:   authUser,
+	}, delivery)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListWebhookEvents returns webhooks received from payment providers,
+// newest first. It needs WithAdminAPIKey.
+func (c *Client) ListWebhookEvents(ctx context.Context, params ListParams) ([]WebhookEvent, error) {
+	events := []WebhookEvent{}
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/admin/webhooks/events",
+		query:  params.query(),
+		auth:   authAdmin,
+	}, &events)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReplayWebhookEvent processes a failed payment provider webhook again. It
+// needs WithAdminAPIKey.
+func (c *Client) ReplayWebhookEvent(ctx context.Context, eventID uuid.UUID) (*WebhookEvent, error) {
+	event := &WebhookEvent{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/admin/webhooks/events/" + eventID.String() + "/replay",
+		auth:   authAdmin,
+	}, event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Reset deletes every user. The server only allows it in dev.
+func (c *Client) Reset(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/admin/reset"}, nil)
+}
+
+// Healthz returns nil when the server is ready
+func (c *Client) Healthz(ctx context.Context) error {
+	var body []byte
+	return c.do(ctx, request{method: http.MethodGet, path: "/api/healthz"}, &body)
+}
+
+// JWKS returns the public keys access tokens are signed with
+func (c *Client) JWKS(ctx context.Context) ([]JWK, error) {
+	jwks := struct {
+		Keys []JWK `json:"keys"`
+	}{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/.well-known/jwks.json"}, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	return jwks.Keys, nil
+}
@@ -0,0 +1,69 @@
+-- name: CreateChirpImport :one
+-- No rows means the user already has a pending import
+INSERT INTO chirp_imports (id, user_id, status, format, archive, next_attempt_at, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, 'pending', $2, $3, $4, NOW(), NOW())
+ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+RETURNING id, user_id, status, format, error, total_rows, imported_rows, duplicate_rows, failed_rows, row_errors, created_at, updated_at, completed_at;
+
+-- name: CountChirpImportsSince :one
+SELECT COUNT(*) FROM chirp_imports
+WHERE user_id = $1 AND created_at > $2;
+
+-- name: GetChirpImport :one
+-- Everything but the archive, which can be large
+SELECT id, user_id, status, format, error, total_rows, imported_rows, duplicate_rows, failed_rows, row_errors, created_at, updated_at, completed_at
+FROM chirp_imports
+WHERE id = $1;
+
+-- name: GetPendingChirpImportArchive :one
+-- Locks the import, a server that runs it again after the lease waits and
+-- then finds it isn't pending anymore
+SELECT user_id, format, archive FROM chirp_imports
+WHERE id = $1 AND status = 'pending'
+FOR UPDATE;
+
+-- name: ClaimDueChirpImports :many
+-- Pushes next_attempt_at forward so an import that is running isn't picked
+-- up again, even by another server, until lease_until
+UPDATE chirp_imports
+SET next_attempt_at = sqlc.arg(lease_until)::timestamptz, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM chirp_imports
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id;
+
+-- name: CompleteChirpImport :exec
+-- The archive isn't needed once its chirps are in
+UPDATE chirp_imports
+SET status = 'completed', archive = NULL, total_rows = $2, imported_rows = $3,
+    duplicate_rows = $4, failed_rows = $5, row_errors = $6, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailChirpImport :exec
+UPDATE chirp_imports
+SET status = 'failed', archive = NULL, error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: LockUserForImport :exec
+-- Imports for the same user run one at a time, so they can't both add a
+-- chirp neither has seen yet
+SELECT id FROM users
+WHERE id = $1
+FOR NO KEY UPDATE;
+
+-- name: ImportChirp :one
+-- Adds a chirp with its original created_at, unless the user already has
+-- one with the same body and created_at. No rows means it's a duplicate.
+INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+SELECT gen_random_uuid(), sqlc.arg(created_at)::timestamptz, NOW(), sqlc.arg(body)::text, sqlc.arg(user_id)::uuid
+WHERE NOT EXISTS (
+    SELECT 1 FROM chirps
+    WHERE chirps.user_id = sqlc.arg(user_id)::uuid
+    AND chirps.created_at = sqlc.arg(created_at)::timestamptz
+    AND chirps.body = sqlc.arg(body)::text
+)
+RETURNING id;
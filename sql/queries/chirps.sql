@@ -23,4 +23,42 @@ WHERE id = $1;
 
 -- name: GetChirpsByAuthorID :many
 SELECT * FROM chirps
-WHERE user_id = $1;
\ No newline at end of file
+WHERE user_id = $1;
+
+-- name: CountChirpsByAuthorSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND created_at > $2;
+
+
+-- name: GetRecentChirpsByAuthor :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: GetRecentChirpsByHashtag :many
+-- tag is matched as a whole word after a #, the same way pubsub.Hashtags
+-- finds hashtags
+SELECT * FROM chirps
+WHERE body ~* ('(^|[^[:alnum:]_])#' || sqlc.arg(tag)::text || '($|[^[:alnum:]_])')
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_chirps);
+
+-- name: ListChirpsPageAsc :many
+-- Keyset pagination, oldest first. The page starts after the chirp with
+-- after_created_at and after_id, or at the beginning when they're null.
+SELECT * FROM chirps
+WHERE (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id)::uuid)
+AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+    OR (created_at, id) > (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(max_chirps);
+
+-- name: ListChirpsPageDesc :many
+-- The same as ListChirpsPageAsc, newest first
+SELECT * FROM chirps
+WHERE (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id)::uuid)
+AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_chirps);
@@ -0,0 +1,54 @@
+-- name: CreateDataExport :one
+-- No rows means the user already has a pending export
+INSERT INTO data_exports (id, user_id, status, next_attempt_at, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, 'pending', NOW(), NOW(), NOW())
+ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+RETURNING id, user_id, status, error, created_at, updated_at, completed_at, expires_at;
+
+-- name: GetPendingDataExport :one
+SELECT id, user_id, status, error, created_at, updated_at, completed_at, expires_at
+FROM data_exports
+WHERE user_id = $1 AND status = 'pending'
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetDataExport :one
+-- Everything but the archive, which can be large
+SELECT id, user_id, status, error, created_at, updated_at, completed_at, expires_at
+FROM data_exports
+WHERE id = $1;
+
+-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1 AND status = 'ready' AND expires_at > NOW();
+
+-- name: ClaimDueDataExports :many
+-- Pushes next_attempt_at forward so an export that is being built isn't
+-- picked up again, even by another server, until lease_until
+UPDATE data_exports
+SET next_attempt_at = sqlc.arg(lease_until)::timestamptz, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM data_exports
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready', archive = $2, error = NULL, completed_at = NOW(), expires_at = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ExpireDataExports :execrows
+-- Drops archives nobody downloaded in time, the export's status stays
+-- around so polling it says what happened
+UPDATE data_exports
+SET status = 'expired', archive = NULL, updated_at = NOW()
+WHERE status = 'ready' AND expires_at <= NOW();
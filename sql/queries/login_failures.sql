@@ -0,0 +1,26 @@
+-- name: GetLoginFailuresForUpdate :one
+-- Held until the attempt is counted, so parallel attempts against the same
+-- key are checked one at a time
+SELECT * FROM login_failures WHERE key = $1
+FOR UPDATE;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (key, failures, last_failure_at)
+VALUES (sqlc.arg(key), 1, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    failures = CASE
+        WHEN login_failures.last_failure_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING *;
+
+-- name: ForgiveLoginFailure :exec
+-- Takes back an attempt that was counted before it turned out to be correct
+UPDATE login_failures SET failures = GREATEST(failures - 1, 0) WHERE key = $1;
+
+-- name: LockLoginKey :exec
+UPDATE login_failures SET locked_until = $2 WHERE key = $1;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1;
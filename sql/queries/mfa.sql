@@ -0,0 +1,28 @@
+-- name: SetUserTOTPSecret :one
+UPDATE users SET totp_secret = $1, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users SET totp_enabled_at = NOW(), updated_at = NOW() WHERE id = $1
+RETURNING *;
+
+-- name: DisableUserTOTP :one
+UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordTOTPStep :execrows
+UPDATE users SET totp_last_step = $1
+WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1);
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (id, code_hash, created_at, user_id)
+VALUES (gen_random_uuid(), $1, NOW(), $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
@@ -0,0 +1,58 @@
+-- name: GetSubscriptionByUserID :one
+SELECT * FROM subscriptions WHERE user_id = $1
+FOR UPDATE;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_end, cancel_at_period_end, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW(), NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = EXCLUDED.status,
+    current_period_end = EXCLUDED.current_period_end,
+    cancel_at_period_end = EXCLUDED.cancel_at_period_end,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: CreateSubscriptionHistory :exec
+INSERT INTO subscription_history (id, subscription_id, event, plan, status, current_period_end, webhook_event_id, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, NOW());
+
+-- name: IsUserChirpyRed :one
+SELECT EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE user_id = $1
+    AND status IN ('active', 'past_due')
+    AND current_period_end > NOW()
+);
+
+-- name: ExpireLapsedSubscriptions :many
+WITH expired AS (
+    UPDATE subscriptions
+    SET status = 'expired', cancel_at_period_end = FALSE, updated_at = NOW()
+    WHERE status IN ('active', 'past_due') AND current_period_end <= NOW()
+    RETURNING id, user_id, plan, status, current_period_end
+), history AS (
+    INSERT INTO subscription_history (id, subscription_id, event, plan, status, current_period_end, created_at)
+    SELECT gen_random_uuid(), id, 'expired', plan, status, current_period_end, NOW()
+    FROM expired
+)
+SELECT user_id, plan, status, current_period_end FROM expired;
+
+-- name: GetActivePlan :one
+SELECT plan FROM subscriptions
+WHERE user_id = $1
+AND status IN ('active', 'past_due')
+AND current_period_end > NOW();
+
+-- name: GetChirpyRedUserIDs :many
+-- The users among ids that IsUserChirpyRed would report as red
+SELECT user_id FROM subscriptions
+WHERE user_id = ANY(sqlc.arg(ids)::uuid[])
+AND status IN ('active', 'past_due')
+AND current_period_end > NOW();
+
+-- name: ListSubscriptionHistoryForUser :many
+SELECT subscription_history.* FROM subscription_history
+JOIN subscriptions ON subscriptions.id = subscription_history.subscription_id
+WHERE subscriptions.user_id = $1
+ORDER BY subscription_history.created_at ASC;
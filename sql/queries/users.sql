@@ -15,13 +15,127 @@ DELETE FROM users;
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
--- name: UpdateUser :one
-UPDATE users SET email = $1, hashed_password = $2 WHERE id = $3
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: UpdateUserPassword :one
+UPDATE users SET hashed_password = $1, updated_at = NOW() WHERE id = $2
 RETURNING *;
 
--- name: UpdateUserIsChirpyRed :one
-UPDATE users SET is_chirpy_red = $1 WHERE id = $2
+-- name: VerifyUserEmail :one
+UPDATE users SET email = $1, email_verified_at = NOW(), updated_at = NOW() WHERE id = $2
 RETURNING *;
 
--- name: GetUserByID :one
-SELECT * FROM users WHERE id = $1;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: ListUsers :many
+-- Newest first. search matches part of the email, case insensitively.
+SELECT users.*, EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE subscriptions.user_id = users.id
+    AND status IN ('active', 'past_due')
+    AND current_period_end > NOW()
+) AS is_chirpy_red
+FROM users
+WHERE sqlc.narg(search)::text IS NULL OR email ILIKE '%' || sqlc.narg(search)::text || '%'
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_users);
+
+-- name: AddUserRole :one
+UPDATE users
+SET roles = CASE WHEN sqlc.arg(role)::text = ANY(roles) THEN roles ELSE array_append(roles, sqlc.arg(role)::text) END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RemoveUserRole :one
+UPDATE users SET roles = array_remove(roles, sqlc.arg(role)::text), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetStats :one
+SELECT
+    (SELECT COUNT(*) FROM users WHERE deleted_at IS NULL) AS users,
+    (SELECT COUNT(*) FROM users WHERE email_verified_at IS NOT NULL) AS verified_users,
+    (SELECT COUNT(*) FROM users WHERE suspended_at IS NOT NULL) AS suspended_users,
+    (SELECT COUNT(*) FROM subscriptions
+        WHERE status IN ('active', 'past_due') AND current_period_end > NOW()) AS chirpy_red_users,
+    (SELECT COUNT(*) FROM chirps) AS chirps,
+    (SELECT COUNT(*) FROM chirps WHERE created_at > NOW() - INTERVAL '24 hours') AS chirps_last_day,
+    (SELECT COUNT(*) FROM refresh_tokens
+        WHERE revoked_at IS NULL AND expires_at > NOW()) AS active_sessions;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users SET delete_after = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users SET delete_after = NULL, updated_at = NOW()
+WHERE id = $1 AND delete_after IS NOT NULL;
+
+-- name: ListUsersDueForDeletion :many
+SELECT id FROM users
+WHERE delete_after <= NOW() AND deleted_at IS NULL
+ORDER BY delete_after
+LIMIT $1;
+
+-- name: LockUserForDeletion :one
+-- Skips users another server is deleting, or who logged in and cancelled
+-- since they were listed
+SELECT * FROM users
+WHERE id = $1 AND delete_after <= NOW() AND deleted_at IS NULL
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: AnonymizeUser :exec
+-- Keeps the row, so the user's chirps survive, without anything that
+-- identifies or authenticates them
+UPDATE users
+SET email = 'deleted-' || id || '@deleted.invalid',
+    hashed_password = '',
+    email_verified_at = NULL,
+    totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = NULL,
+    roles = '{}',
+    suspended_at = NULL,
+    delete_after = NULL,
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUserData :exec
+-- Everything that references a user except their chirps
+WITH refresh AS (
+    DELETE FROM refresh_tokens WHERE refresh_tokens.user_id = sqlc.arg(user_id)
+), pats AS (
+    DELETE FROM personal_access_tokens WHERE personal_access_tokens.user_id = sqlc.arg(user_id)
+), resets AS (
+    DELETE FROM password_reset_tokens WHERE password_reset_tokens.user_id = sqlc.arg(user_id)
+), verifications AS (
+    DELETE FROM email_verification_tokens WHERE email_verification_tokens.user_id = sqlc.arg(user_id)
+), recovery AS (
+    DELETE FROM mfa_recovery_codes WHERE mfa_recovery_codes.user_id = sqlc.arg(user_id)
+), subs AS (
+    DELETE FROM subscriptions WHERE subscriptions.user_id = sqlc.arg(user_id)
+), webhooks AS (
+    DELETE FROM webhook_subscriptions WHERE webhook_subscriptions.user_id = sqlc.arg(user_id)
+), imports AS (
+    DELETE FROM chirp_imports WHERE chirp_imports.user_id = sqlc.arg(user_id)
+)
+DELETE FROM data_exports WHERE data_exports.user_id = sqlc.arg(user_id);
@@ -0,0 +1,33 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (id, provider, event_id, event_type, payload, status, received_at, claimed_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, 'processing', NOW(), NOW())
+ON CONFLICT (provider, event_id) DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookEventByEventID :one
+SELECT * FROM webhook_events WHERE provider = $1 AND event_id = $2;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events WHERE id = $1;
+
+-- Takes failed events, and events left processing since before
+-- stale_before by a server that stopped before finishing them
+-- name: ClaimWebhookEvent :one
+UPDATE webhook_events SET status = 'processing', claimed_at = NOW()
+WHERE id = $1 AND (
+    status = 'failed'
+    OR (status = 'processing' AND claimed_at < sqlc.arg(stale_before)::timestamptz)
+)
+RETURNING *;
+
+-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $2, error = $3, attempts = attempts + 1, processed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text
+ORDER BY received_at DESC
+LIMIT $1;
@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX subscriptions_status_period_end_idx ON subscriptions (status, current_period_end);
+
+CREATE TABLE subscription_history (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    webhook_event_id UUID REFERENCES webhook_events(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX subscription_history_subscription_id_idx ON subscription_history (subscription_id, created_at);
+
+-- Upgrades used to be permanent and Polka never sends renewals for them, so
+-- existing members keep an active subscription that doesn't run out. A
+-- downgrade still ends it like any other. The period ends at the end of
+-- year 9999 rather than 'infinity', which lib/pq can't scan into a time.Time
+INSERT INTO subscriptions (id, user_id, plan, status, current_period_end, created_at, updated_at)
+SELECT gen_random_uuid(), id, 'chirpy_red', 'active', TIMESTAMPTZ '9999-12-31 23:59:59+00', NOW(), NOW()
+FROM users WHERE is_chirpy_red;
+
+INSERT INTO subscription_history (id, subscription_id, event, plan, status, current_period_end, created_at)
+SELECT gen_random_uuid(), id, 'upgraded', plan, status, current_period_end, NOW()
+FROM subscriptions;
+
+ALTER TABLE users DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOLEAN DEFAULT FALSE;
+
+UPDATE users SET is_chirpy_red = TRUE
+WHERE id IN (
+    SELECT user_id FROM subscriptions
+    WHERE status IN ('active', 'past_due') AND current_period_end > NOW()
+);
+
+DROP TABLE subscription_history;
+DROP TABLE subscriptions;
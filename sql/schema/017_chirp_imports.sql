@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE chirp_imports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    format TEXT NOT NULL,
+    archive BYTEA,
+    error TEXT,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    imported_rows INTEGER NOT NULL DEFAULT 0,
+    duplicate_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    row_errors JSONB NOT NULL DEFAULT '[]',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX chirp_imports_due_idx ON chirp_imports (status, next_attempt_at);
+-- A user can only have one import waiting to run
+CREATE UNIQUE INDEX chirp_imports_one_pending_idx ON chirp_imports (user_id) WHERE status = 'pending';
+CREATE INDEX chirp_imports_user_id_created_at_idx ON chirp_imports (user_id, created_at);
+-- Finds the chirp an imported one would duplicate
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at);
+
+-- +goose Down
+DROP INDEX chirps_user_id_created_at_idx;
+DROP TABLE chirp_imports;
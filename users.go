@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/mail"
 	"time"
 
 	"github.com/RodolfoCamposGlz/internal/auth"
@@ -28,6 +32,17 @@ type UserResponse struct {
 	Token        string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 	IsChirpyRed bool   `json:"is_chirpy_red"`
+	IsEmailVerified bool `json:"is_email_verified"`
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+// validateEmail accepts bare addresses such as user@example.com
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("invalid email address")
+	}
+	return nil
 }
 
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -39,21 +54,9 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "Error decoding JSON")
 		return
 	}
-	dbQueries := cfg.dbQueries
-
-	hashedPassword, err := auth.HashPassword(user.Password)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error hashing password")
-		return
-	}	
-	params := database.CreateUserParams{
-		Email:          user.Email,
-		HashedPassword: hashedPassword,
-	}
-	createdUser, err := dbQueries.CreateUser(r.Context(), params)
-	if err != nil {
-		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusInternalServerError, "Error creating user")
+	createdUser, apiErr := cfg.createUser(r.Context(), user.Email, user.Password)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
 		return
 	}
 	response := UserResponse{
@@ -62,10 +65,32 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		CreatedAt: createdUser.CreatedAt.Time,
 		UpdatedAt: createdUser.UpdatedAt.Time,
 	}
-	
 	respondWithJSON(w, http.StatusCreated, response)
 }
 
+// createUser registers a user and sends them a verification email
+func (cfg *apiConfig) createUser(ctx context.Context, email, password string) (database.User, *apiError) {
+	if err := validateEmail(email); err != nil {
+		return database.User{}, &apiError{status: http.StatusBadRequest, message: "Invalid email address"}
+	}
+	hashedPassword, err := cfg.passwordHasher.Hash(password)
+	if err != nil {
+		return database.User{}, &apiError{status: http.StatusInternalServerError, message: "Error hashing password"}
+	}
+	params := database.CreateUserParams{
+		Email:          email,
+		HashedPassword: hashedPassword,
+	}
+	createdUser, err := cfg.dbQueries.CreateUser(ctx, params)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return database.User{}, &apiError{status: http.StatusInternalServerError, message: "Error creating user"}
+	}
+
+	go cfg.sendEmailVerification(createdUser.ID, createdUser.Email)
+	return createdUser, nil
+}
+
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	user := User{}
@@ -74,39 +99,170 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Error decoding JSON")
 		return
 	}
-	dbQueries := cfg.dbQueries
-	params := database.CreateUserParams{
-		Email:          user.Email,
+	getUser, mfaToken, apiErr := cfg.login(r.Context(), user.Email, user.Password, cfg.clientIP(r))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
 	}
-	getUser, err := dbQueries.GetUserByEmail(r.Context(), params.Email)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+	if mfaToken != "" {
+		respondWithJSON(w, http.StatusOK, mfaChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
 		return
 	}
-	isCorrectPassword := auth.CheckPasswordHash(user.Password, getUser.HashedPassword)
+	cfg.respondWithSession(w, r, getUser)
+}
+
+var errAccountSuspended = &apiError{status: http.StatusForbidden, message: "Account suspended"}
+
+// login checks an email and password from ip. With two-factor enabled the
+// password alone only earns a challenge token that can be exchanged with
+// loginMFA, it's returned instead of starting a session.
+func (cfg *apiConfig) login(ctx context.Context, email, password, ip string) (database.User, string, *apiError) {
+	throttles := cfg.loginThrottles(ip, email)
+	attempt, retryAfter, err := cfg.beginLoginAttempt(ctx, ip, throttles)
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return database.User{}, "", &apiError{status: http.StatusInternalServerError, message: "Error checking login attempts"}
+	}
+	if retryAfter > 0 {
+		return database.User{}, "", errTooManyAttempts(retryAfter)
+	}
+
+	getUser, err := cfg.dbQueries.GetUserByEmail(ctx, email)
+	userExists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error: %v\n", err)
+		return database.User{}, "", &apiError{status: http.StatusInternalServerError, message: "Error getting user"}
+	}
+	// Unknown emails are checked against a dummy hash so they take as long,
+	// and fail the same way, as a wrong password
+	hashedPassword := cfg.dummyPasswordHash
+	if userExists {
+		hashedPassword = getUser.HashedPassword
+	}
+	isCorrectPassword := auth.CheckPasswordHash(password, hashedPassword) && userExists
 	if !isCorrectPassword {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		var owner *database.User
+		if userExists {
+			owner = &getUser
+		}
+		cfg.recordLoginFailure(ctx, attempt, owner)
+		return database.User{}, "", &apiError{status: http.StatusUnauthorized, message: "Incorrect email or password"}
+	}
+	cfg.forgiveLoginAttempt(ctx, attempt)
+
+	// Upgrade hashes made with an older algorithm or weaker parameters now
+	// that we have the plaintext password
+	if cfg.passwordHasher.NeedsRehash(getUser.HashedPassword) {
+		cfg.rehashPassword(ctx, getUser.ID, password)
+	}
+
+	// Checked after the password so it doesn't reveal which emails exist
+	if getUser.SuspendedAt.Valid {
+		return database.User{}, "", errAccountSuspended
+	}
+
+	if getUser.TotpEnabledAt.Valid {
+		mfaToken, err := cfg.keyring.MakeMFAToken(getUser.ID, mfaChallengeTTL)
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			return database.User{}, "", &apiError{status: http.StatusInternalServerError, message: "Couldn't create MFA token"}
+		}
+		return getUser, mfaToken, nil
+	}
+
+	cfg.clearLoginFailures(ctx, throttles)
+	return getUser, "", nil
+}
+
+// reauthenticate checks the password, and the second factor when it's
+// enabled, of a signed-in user before a sensitive change. Attempts count
+// against the same throttles as logging in, so a stolen access token can't
+// be used to guess the password.
+func (cfg *apiConfig) reauthenticate(ctx context.Context, ip string, user database.User, password, code string) *apiError {
+	attempt, retryAfter, err := cfg.beginLoginAttempt(ctx, ip, cfg.loginThrottles(ip, user.Email))
+	if err != nil {
+		log.Printf("Error: %v\n", err)
+		return &apiError{status: http.StatusInternalServerError, message: "Error checking login attempts"}
+	}
+	if retryAfter > 0 {
+		return errTooManyAttempts(retryAfter)
+	}
+
+	errIncorrect := &apiError{status: http.StatusUnauthorized, message: "Incorrect password or code"}
+	if !auth.CheckPasswordHash(password, user.HashedPassword) {
+		cfg.recordLoginFailure(ctx, attempt, &user)
+		return errIncorrect
+	}
+	if user.TotpEnabledAt.Valid {
+		if cfg.mfaEncryptionKey == nil {
+			cfg.forgiveLoginAttempt(ctx, attempt)
+			return &apiError{status: http.StatusNotImplemented, message: "Two-factor authentication is not configured"}
+		}
+		valid, err := cfg.verifySecondFactor(ctx, user, code)
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			return &apiError{status: http.StatusInternalServerError, message: "Error verifying code"}
+		}
+		if !valid {
+			cfg.recordLoginFailure(ctx, attempt, &user)
+			return errIncorrect
+		}
+	}
+	cfg.forgiveLoginAttempt(ctx, attempt)
+	return nil
+}
+
+// rehashPassword stores a fresh hash of a password that was just verified.
+// Failures are only logged, the old hash still works.
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashedPassword, err := cfg.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Error rehashing password: %v\n", err)
+		return
+	}
+	_, err = cfg.dbQueries.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             userID,
+	})
+	if err != nil {
+		log.Printf("Error rehashing password: %v\n", err)
+	}
+}
+
+// respondWithSession issues an access and refresh token pair for a user who
+// has fully authenticated
+func (cfg *apiConfig) respondWithSession(w http.ResponseWriter, r *http.Request, getUser database.User) {
+	response, apiErr := cfg.newSession(r.Context(), getUser)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
 		return
 	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// newSession issues an access and refresh token pair for a user who has
+// fully authenticated. Logging in cancels a pending account deletion.
+func (cfg *apiConfig) newSession(ctx context.Context, getUser database.User) (UserResponse, *apiError) {
+	cfg.cancelAccountDeletion(ctx, getUser)
 
 	expirationTime := time.Hour
 
-	accessToken, err := auth.MakeJWT(
+	accessToken, err := cfg.keyring.MakeJWT(
 		getUser.ID,
-		cfg.jwtSecret,
 		expirationTime,
 	)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusInternalServerError, "Couldn't create access JWT")
-		return
+		return UserResponse{}, &apiError{status: http.StatusInternalServerError, message: "Couldn't create access JWT"}
 	}
 
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
 		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusInternalServerError, "Couldn't create refresh JWT")
-		return
+		return UserResponse{}, &apiError{status: http.StatusInternalServerError, message: "Couldn't create refresh JWT"}
 	}
 
 
@@ -117,11 +273,10 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		RevokedAt: sql.NullTime{},
 	}
 
-	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), refreshTokenParams)
+	_, err = cfg.dbQueries.CreateRefreshToken(ctx, refreshTokenParams)
 	if err != nil {
 		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusInternalServerError, "Couldn't store refresh token")
-		return
+		return UserResponse{}, &apiError{status: http.StatusInternalServerError, message: "Couldn't store refresh token"}
 	}
 
 	response := UserResponse{
@@ -131,54 +286,105 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: getUser.UpdatedAt.Time,
 		Token:        accessToken,
 		RefreshToken: refreshToken,
-		IsChirpyRed: getUser.IsChirpyRed.Bool,
+		IsChirpyRed: cfg.isChirpyRed(ctx, getUser.ID),
+		IsEmailVerified: getUser.EmailVerifiedAt.Valid,
 	}
-	respondWithJSON(w, http.StatusOK, response)
+	return response, nil
 }
 
-func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT")
-		return
-	}
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid JWT")
-		return
-	}
+func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
 	type request struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		CurrentPassword string `json:"current_password"`
+		Code string `json:"code"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	req := request{}
-	err = decoder.Decode(&req)
+	err := decoder.Decode(&req)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error decoding JSON")
 		return
 	}
-	hashedPassword, err := auth.HashPassword(req.Password)
+
+	// Both the email and the password can be used to take the account over,
+	// a leaked personal access token mustn't be able to change either. Only
+	// a session that knows the current password can
+	if req.Email != "" || req.Password != "" {
+		token, _ := auth.GetBearerToken(r.Header)
+		if auth.IsPersonalAccessToken(token) {
+			respondWithError(w, http.StatusForbidden, "Personal access tokens can't change the email or password")
+			return
+		}
+		if req.CurrentPassword == "" {
+			respondWithError(w, http.StatusUnauthorized, "Current password is required to change the email or password")
+			return
+		}
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error hashing password")
+		respondWithError(w, http.StatusNotFound, "User not found")
 		return
 	}
-	params := database.UpdateUserParams{
-		Email:          req.Email,
-		HashedPassword: hashedPassword,
-		ID:             userID,
+
+	// A new email only replaces the current one once it has been confirmed
+	pendingEmail := ""
+	if req.Email != "" && req.Email != user.Email {
+		if err := validateEmail(req.Email); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid email address")
+			return
+		}
+		pendingEmail = req.Email
 	}
-	updatedUser, err := cfg.dbQueries.UpdateUser(r.Context(), params)
-	if err != nil {
-		log.Printf("Error: %v\n", err)
-		respondWithError(w, http.StatusInternalServerError, "Error updating user")
-		return
+
+	if pendingEmail != "" || req.Password != "" {
+		apiErr := cfg.reauthenticate(r.Context(), cfg.clientIP(r), user, req.CurrentPassword, req.Code)
+		if apiErr != nil {
+			respondWithAPIError(w, apiErr)
+			return
+		}
+	}
+
+	// Checked after the password so the endpoint can't be used to find out
+	// which emails have an account
+	if pendingEmail != "" {
+		_, err := cfg.dbQueries.GetUserByEmail(r.Context(), pendingEmail)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "Email is already in use")
+			return
+		}
 	}
+
+	if req.Password != "" {
+		hashedPassword, err := cfg.passwordHasher.Hash(req.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error hashing password")
+			return
+		}
+		user, err = cfg.dbQueries.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			HashedPassword: hashedPassword,
+			ID:             userID,
+		})
+		if err != nil {
+			log.Printf("Error: %v\n", err)
+			respondWithError(w, http.StatusInternalServerError, "Error updating user")
+			return
+		}
+	}
+
+	if pendingEmail != "" {
+		go cfg.sendEmailVerification(user.ID, pendingEmail)
+	}
+
 	response := UserResponse{
-		ID:        updatedUser.ID,
-		Email:     updatedUser.Email,
-		CreatedAt: updatedUser.CreatedAt.Time,
-		UpdatedAt: updatedUser.UpdatedAt.Time,
+		ID:        user.ID,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt.Time,
+		UpdatedAt: user.UpdatedAt.Time,
+		IsChirpyRed: cfg.isChirpyRed(r.Context(), user.ID),
+		IsEmailVerified: user.EmailVerifiedAt.Valid,
+		PendingEmail: pendingEmail,
 	}
 	respondWithJSON(w, http.StatusOK, response)
 
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RodolfoCamposGlz/internal/auth"
+	"github.com/google/uuid"
+)
+
+// The refusals happen before the user is loaded, so the config needs no
+// database
+func TestUpdateUserRequiresSession(t *testing.T) {
+	cfg := &apiConfig{keyring: newTestKeyring(t, "users-test-secret")}
+	userID := uuid.New()
+	jwt, err := cfg.keyring.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pat, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "Email with a personal access token", token: pat, body: `{"email":"new@example.com","current_password":"password123"}`, wantStatus: http.StatusForbidden},
+		{name: "Password with a personal access token", token: pat, body: `{"password":"newpassword123","current_password":"password123"}`, wantStatus: http.StatusForbidden},
+		{name: "Email without the current password", token: jwt, body: `{"email":"new@example.com"}`, wantStatus: http.StatusUnauthorized},
+		{name: "Password without the current password", token: jwt, body: `{"password":"newpassword123"}`, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/users", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			cfg.handlerUpdateUser(w, req, userID)
+			if w.Code != tt.wantStatus {
+				t.Errorf("handlerUpdateUser() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}